@@ -0,0 +1,202 @@
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"log"
+	"material_todo_go/database"
+	"material_todo_go/models"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Event names
+const (
+	EventLoginSucceeded       = "login.succeeded"
+	EventLoginFailed          = "login.failed"
+	EventSignup               = "signup"
+	EventResetCodeSent        = "password.reset_code_sent"
+	EventPasswordReset        = "password.reset"
+	EventPasswordResetFailed  = "password.reset_failed"
+	EventPasswordChanged      = "password.changed"
+	EventPasswordChangeFailed = "password.change_failed"
+	EventEmailChanged         = "email.changed"
+	EventProfileUpdated       = "profile.updated"
+	EventTokenRejected        = "token.rejected"
+	EventAdminUserDisabled    = "admin.user_disabled"
+	EventAdminUserEnabled     = "admin.user_enabled"
+	EventAdminRoleChanged     = "admin.role_changed"
+	EventAdminPasswordForced  = "admin.password_reset_forced"
+)
+
+// chainLock serializes appends so every entry links to the one before it
+const chainLock = 7245310
+
+// Events wait in a queue for a single writer, so requests never contend for chainLock themselves
+const (
+	queueSize = 1000
+	batchSize = 100
+)
+
+var (
+	queue     = make(chan *models.AuditEvent, queueSize)
+	startOnce sync.Once
+	dropped   int64
+)
+
+// Entry describes an event to record; IP and user agent are taken from the request
+type Entry struct {
+	Event     string
+	ActorID   uint
+	SubjectID uint
+	Email     string
+	Metadata  map[string]interface{}
+}
+
+// Record queues an event for the current request. Failures are logged and never fail the request.
+// When the queue is full, events without an authenticated actor are dropped rather than slowing
+// anyone down, so unauthenticated traffic cannot hold up the requests of signed-in users.
+func Record(c *gin.Context, entry Entry) {
+	startOnce.Do(func() { go write() })
+
+	metadata := "{}"
+	if len(entry.Metadata) > 0 {
+		if encoded, err := json.Marshal(entry.Metadata); err == nil {
+			metadata = string(encoded)
+		}
+	}
+
+	event := &models.AuditEvent{
+		Event:     entry.Event,
+		ActorID:   entry.ActorID,
+		SubjectID: entry.SubjectID,
+		Email:     strings.ToLower(entry.Email),
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Metadata:  metadata,
+		// Postgres keeps microseconds, so hash exactly what will be read back
+		CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+	}
+
+	if entry.ActorID != 0 {
+		queue <- event
+		return
+	}
+	select {
+	case queue <- event:
+	default:
+		if n := atomic.AddInt64(&dropped, 1); n == 1 || n%1000 == 0 {
+			log.Printf("Audit queue full, dropped %d unauthenticated events so far", n)
+		}
+	}
+}
+
+// write appends queued events in batches, taking chainLock once per batch
+func write() {
+	for event := range queue {
+		batch := []*models.AuditEvent{event}
+	fill:
+		for len(batch) < batchSize {
+			select {
+			case next := <-queue:
+				batch = append(batch, next)
+			default:
+				break fill
+			}
+		}
+
+		if err := Append(batch...); err != nil {
+			log.Printf("Failed to record %d audit events: %v", len(batch), err)
+		}
+	}
+}
+
+// Append links the events to the end of the chain, in order, and stores them
+func Append(events ...*models.AuditEvent) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", chainLock).Error; err != nil {
+			return err
+		}
+
+		var last models.AuditEvent
+		if err := tx.Order("id DESC").Limit(1).Find(&last).Error; err != nil {
+			return err
+		}
+
+		link(events, last.Hash)
+		for _, event := range events {
+			if err := tx.Create(event).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// link chains the events after prevHash, in order
+func link(events []*models.AuditEvent, prevHash string) {
+	for _, event := range events {
+		if event.CreatedAt.IsZero() {
+			event.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+		}
+		event.PrevHash = prevHash
+		event.Hash = Hash(*event)
+		prevHash = event.Hash
+	}
+}
+
+// Hash computes the chain hash of an event from its content and the previous hash
+func Hash(event models.AuditEvent) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%d|%d|%s|%s|%s|%s|%s",
+		event.PrevHash,
+		event.Event,
+		event.ActorID,
+		event.SubjectID,
+		event.Email,
+		event.IP,
+		event.UserAgent,
+		event.Metadata,
+		event.CreatedAt.UTC().Format(time.RFC3339Nano),
+	)))
+	return hex.EncodeToString(sum[:])
+}
+
+// Verify walks the whole chain and returns the ID of the first entry that does not match, or 0 if it is intact
+func Verify() (uint, error) {
+	var brokenID uint
+	prevHash := ""
+
+	var batch []models.AuditEvent
+	result := database.DB.FindInBatches(&batch, 500, func(tx *gorm.DB, _ int) error {
+		brokenID, prevHash = verifyChain(batch, prevHash)
+		if brokenID != 0 {
+			return errChainBroken
+		}
+		return nil
+	})
+	if result.Error != nil && result.Error != errChainBroken {
+		return 0, result.Error
+	}
+	return brokenID, nil
+}
+
+// verifyChain checks that the events follow prevHash and each other. It returns the ID of the
+// first entry that does not match, or 0 and the hash the next entry must link to.
+func verifyChain(events []models.AuditEvent, prevHash string) (uint, string) {
+	for _, event := range events {
+		if event.PrevHash != prevHash || Hash(event) != event.Hash {
+			return event.ID, prevHash
+		}
+		prevHash = event.Hash
+	}
+	return 0, prevHash
+}
+
+var errChainBroken = errors.New("audit chain broken")
@@ -0,0 +1,121 @@
+package audit
+
+import (
+	"material_todo_go/models"
+	"testing"
+	"time"
+)
+
+// chain builds three linked events following prevHash
+func chain(prevHash string) []models.AuditEvent {
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 123456000, time.UTC)
+	events := []*models.AuditEvent{
+		{ID: 1, Event: EventSignup, SubjectID: 1, Email: "a@example.com", IP: "10.0.0.1", Metadata: "{}", CreatedAt: createdAt},
+		{ID: 2, Event: EventLoginSucceeded, ActorID: 1, SubjectID: 1, Email: "a@example.com", IP: "10.0.0.1", UserAgent: "curl", Metadata: "{}", CreatedAt: createdAt.Add(time.Second)},
+		{ID: 3, Event: EventAdminRoleChanged, ActorID: 1, SubjectID: 2, Metadata: `{"role":"admin"}`, CreatedAt: createdAt.Add(2 * time.Second)},
+	}
+	link(events, prevHash)
+
+	linked := make([]models.AuditEvent, len(events))
+	for i, event := range events {
+		linked[i] = *event
+	}
+	return linked
+}
+
+func TestHash(t *testing.T) {
+	event := chain("")[1]
+	hash := Hash(event)
+
+	if len(hash) != 64 || hash != event.Hash {
+		t.Fatalf("Hash = %q, want the stored 64-character hash %q", hash, event.Hash)
+	}
+
+	// The same instant in another location hashes the same
+	moved := event
+	moved.CreatedAt = event.CreatedAt.In(time.FixedZone("UTC+2", 2*60*60))
+	if Hash(moved) != hash {
+		t.Error("hash depends on the time zone")
+	}
+
+	tests := []struct {
+		name   string
+		change func(*models.AuditEvent)
+	}{
+		{"previous hash", func(e *models.AuditEvent) { e.PrevHash = "other" }},
+		{"event", func(e *models.AuditEvent) { e.Event = EventLoginFailed }},
+		{"actor", func(e *models.AuditEvent) { e.ActorID = 2 }},
+		{"subject", func(e *models.AuditEvent) { e.SubjectID = 2 }},
+		{"email", func(e *models.AuditEvent) { e.Email = "b@example.com" }},
+		{"ip", func(e *models.AuditEvent) { e.IP = "10.0.0.2" }},
+		{"user agent", func(e *models.AuditEvent) { e.UserAgent = "wget" }},
+		{"metadata", func(e *models.AuditEvent) { e.Metadata = `{"x":1}` }},
+		{"time", func(e *models.AuditEvent) { e.CreatedAt = e.CreatedAt.Add(time.Microsecond) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changed := event
+			tt.change(&changed)
+			if Hash(changed) == hash {
+				t.Errorf("changing the %s does not change the hash", tt.name)
+			}
+		})
+	}
+}
+
+func TestVerifyChain(t *testing.T) {
+	tests := []struct {
+		name     string
+		prevHash string
+		tamper   func([]models.AuditEvent) []models.AuditEvent
+		wantID   uint
+	}{
+		{"intact", "", func(e []models.AuditEvent) []models.AuditEvent { return e }, 0},
+		{"edited entry", "", func(e []models.AuditEvent) []models.AuditEvent {
+			e[1].Email = "someone-else@example.com"
+			return e
+		}, 2},
+		{"edited entry with a recomputed hash", "", func(e []models.AuditEvent) []models.AuditEvent {
+			e[1].Email = "someone-else@example.com"
+			e[1].Hash = Hash(e[1])
+			return e
+		}, 3},
+		{"deleted entry", "", func(e []models.AuditEvent) []models.AuditEvent {
+			return append(e[:1], e[2:]...)
+		}, 3},
+		{"deleted first entry", "", func(e []models.AuditEvent) []models.AuditEvent { return e[1:] }, 2},
+		{"reordered entries", "", func(e []models.AuditEvent) []models.AuditEvent {
+			e[1], e[2] = e[2], e[1]
+			return e
+		}, 3},
+		{"chain starts elsewhere", "unexpected", func(e []models.AuditEvent) []models.AuditEvent { return e }, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := tt.tamper(chain(""))
+			brokenID, _ := verifyChain(events, tt.prevHash)
+			if brokenID != tt.wantID {
+				t.Errorf("verifyChain = %d, want %d", brokenID, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestVerifyChainAcrossBatches(t *testing.T) {
+	first := chain("")
+	second := chain(first[len(first)-1].Hash)
+	for i := range second {
+		second[i].ID += uint(len(first))
+	}
+
+	brokenID, prevHash := verifyChain(first, "")
+	if brokenID != 0 || prevHash != first[len(first)-1].Hash {
+		t.Fatalf("verifyChain(first) = (%d, %q)", brokenID, prevHash)
+	}
+	if brokenID, _ := verifyChain(second, prevHash); brokenID != 0 {
+		t.Errorf("verifyChain(second) = %d, want 0", brokenID)
+	}
+	if brokenID, _ := verifyChain(second, ""); brokenID != second[0].ID {
+		t.Errorf("verifyChain(second) without the first batch = %d, want %d", brokenID, second[0].ID)
+	}
+}
@@ -3,7 +3,11 @@ package config
 import (
 	"fmt"
 	"github.com/joho/godotenv"
+	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 var (
@@ -13,8 +17,84 @@ var (
 	DBPassword string
 	DBName     string
 	JWTSecret  string
+
+	JWTAlgorithm       string
+	JWTPreviousSecrets []string
+	JWTKeyRotation     time.Duration
+
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+
+	AppName       string
+	MailDriver    string
+	MailFrom      string
+	MailOutboxDir string
+	SMTPHost      string
+	SMTPPort      string
+	SMTPUsername  string
+	SMTPPassword  string
+
+	// UnverifiedLoginPolicy is "deny" to refuse Login until the email is verified, or "allow"
+	UnverifiedLoginPolicy string
+
+	// LoginAttemptStore is "memory" for a single instance or "postgres" to share counters between instances
+	LoginAttemptStore     string
+	LoginLockoutThreshold int
+	LoginLockoutDuration  time.Duration
+
+	PasswordMinLength     int
+	PasswordMaxLength     int
+	PasswordBreachedList  string // optional file of breached passwords or their SHA-1 digests
+	PasswordHashAlgorithm string // "bcrypt" or "argon2id"
+	BcryptCost            int
+	Argon2Memory          int // KiB
+	Argon2Iterations      int
+	Argon2Threads         int
+
+	AccountDeletionGracePeriod time.Duration
+
+	// AdminEmails are promoted to the admin role at startup
+	AdminEmails []string
+
+	// OAuthProviders are the social login providers listed in OAUTH_PROVIDERS, keyed by name
+	OAuthProviders map[string]OAuthProvider
+	// OAuthClientRedirects are the client URIs (such as app deep links) allowed to receive login codes
+	OAuthClientRedirects []string
 )
 
+// OAuthProvider configures an OAuth2 / OpenID Connect identity provider
+type OAuthProvider struct {
+	Name         string
+	Type         string // "oidc" or "github"
+	ClientID     string
+	ClientSecret string
+	AuthURL      string
+	TokenURL     string
+	UserInfoURL  string
+	EmailsURL    string // github only
+	RedirectURL  string // this backend's callback URL registered with the provider
+	Scopes       []string
+}
+
+// oauthDefaults fills in the endpoints of well-known providers
+var oauthDefaults = map[string]OAuthProvider{
+	"google": {
+		Type:        "oidc",
+		AuthURL:     "https://accounts.google.com/o/oauth2/v2/auth",
+		TokenURL:    "https://oauth2.googleapis.com/token",
+		UserInfoURL: "https://openidconnect.googleapis.com/v1/userinfo",
+		Scopes:      []string{"openid", "email", "profile"},
+	},
+	"github": {
+		Type:        "github",
+		AuthURL:     "https://github.com/login/oauth/authorize",
+		TokenURL:    "https://github.com/login/oauth/access_token",
+		UserInfoURL: "https://api.github.com/user",
+		EmailsURL:   "https://api.github.com/user/emails",
+		Scopes:      []string{"read:user", "user:email"},
+	},
+}
+
 func LoadConfig() {
 	err := godotenv.Load()
 	if err != nil {
@@ -28,5 +108,133 @@ func LoadConfig() {
 	DBName = os.Getenv("DB_NAME")
 	JWTSecret = os.Getenv("JWT_SECRET")
 
+	JWTAlgorithm = getEnv("JWT_ALGORITHM", "HS256")
+	JWTPreviousSecrets = getList("JWT_PREVIOUS_SECRETS")
+	JWTKeyRotation = getDuration("JWT_KEY_ROTATION", 30*24*time.Hour)
+
+	AccessTokenTTL = getDuration("ACCESS_TOKEN_TTL", 15*time.Minute)
+	RefreshTokenTTL = getDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour)
+
+	AppName = getEnv("APP_NAME", "Material To-Do")
+	MailDriver = getEnv("MAIL_DRIVER", "outbox")
+	MailFrom = getEnv("MAIL_FROM", "no-reply@material-todo.local")
+	MailOutboxDir = getEnv("MAIL_OUTBOX_DIR", "outbox")
+	SMTPHost = os.Getenv("SMTP_HOST")
+	SMTPPort = getEnv("SMTP_PORT", "587")
+	SMTPUsername = os.Getenv("SMTP_USERNAME")
+	SMTPPassword = os.Getenv("SMTP_PASSWORD")
+
+	// An unknown policy must not quietly let unverified accounts in
+	UnverifiedLoginPolicy = strings.ToLower(strings.TrimSpace(getEnv("UNVERIFIED_LOGIN_POLICY", "deny")))
+	if UnverifiedLoginPolicy != "deny" && UnverifiedLoginPolicy != "allow" {
+		log.Fatalf("❌ UNVERIFIED_LOGIN_POLICY must be \"deny\" or \"allow\", got %q", os.Getenv("UNVERIFIED_LOGIN_POLICY"))
+	}
+
+	LoginAttemptStore = getEnv("LOGIN_ATTEMPT_STORE", "memory")
+	LoginLockoutThreshold = getInt("LOGIN_LOCKOUT_THRESHOLD", 10)
+	LoginLockoutDuration = getDuration("LOGIN_LOCKOUT_DURATION", 30*time.Minute)
+
+	PasswordMinLength = getInt("PASSWORD_MIN_LENGTH", 8)
+	PasswordMaxLength = getInt("PASSWORD_MAX_LENGTH", 128)
+	PasswordBreachedList = os.Getenv("PASSWORD_BREACHED_LIST")
+	PasswordHashAlgorithm = getEnv("PASSWORD_HASH_ALGORITHM", "bcrypt")
+	BcryptCost = getInt("BCRYPT_COST", 14)
+	Argon2Memory = getInt("ARGON2_MEMORY", 64*1024)
+	Argon2Iterations = getInt("ARGON2_ITERATIONS", 3)
+	Argon2Threads = getInt("ARGON2_THREADS", 2)
+
+	AccountDeletionGracePeriod = getDuration("ACCOUNT_DELETION_GRACE_PERIOD", 14*24*time.Hour)
+
+	AdminEmails = getList("ADMIN_EMAILS")
+
+	OAuthProviders = loadOAuthProviders()
+	OAuthClientRedirects = getList("OAUTH_CLIENT_REDIRECTS")
+
 	fmt.Println("✅ Environment variables loaded")
 }
+
+// getEnv returns the environment variable or fallback when it is not set
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// getDuration parses a duration such as "15m" or "720h", falling back when it is missing or invalid
+func getDuration(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		fmt.Printf("Invalid duration for %s, using %s\n", key, fallback)
+		return fallback
+	}
+	return d
+}
+
+// getList splits a comma separated variable, ignoring empty entries
+func getList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// getInt parses an integer variable, falling back when it is missing or invalid
+func getInt(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		fmt.Printf("Invalid integer for %s, using %d\n", key, fallback)
+		return fallback
+	}
+	return n
+}
+
+// loadOAuthProviders reads OAUTH_<NAME>_* variables for every provider in OAUTH_PROVIDERS
+func loadOAuthProviders() map[string]OAuthProvider {
+	providers := map[string]OAuthProvider{}
+	for _, name := range getList("OAUTH_PROVIDERS") {
+		name = strings.ToLower(name)
+		prefix := "OAUTH_" + strings.ToUpper(name) + "_"
+		defaults := oauthDefaults[name]
+
+		scopes := strings.Fields(os.Getenv(prefix + "SCOPES"))
+		if len(scopes) == 0 {
+			scopes = defaults.Scopes
+		}
+		if len(scopes) == 0 {
+			scopes = []string{"openid", "email", "profile"}
+		}
+
+		providerType := defaults.Type
+		if providerType == "" {
+			providerType = "oidc"
+		}
+
+		providers[name] = OAuthProvider{
+			Name:         name,
+			Type:         getEnv(prefix+"TYPE", providerType),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			AuthURL:      getEnv(prefix+"AUTH_URL", defaults.AuthURL),
+			TokenURL:     getEnv(prefix+"TOKEN_URL", defaults.TokenURL),
+			UserInfoURL:  getEnv(prefix+"USERINFO_URL", defaults.UserInfoURL),
+			EmailsURL:    getEnv(prefix+"EMAILS_URL", defaults.EmailsURL),
+			RedirectURL:  os.Getenv(prefix + "REDIRECT_URL"),
+			Scopes:       scopes,
+		}
+	}
+	return providers
+}
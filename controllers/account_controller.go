@@ -0,0 +1,186 @@
+package controllers
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"io"
+	"log"
+	"material_todo_go/config"
+	"material_todo_go/database"
+	"material_todo_go/mailer"
+	"material_todo_go/middleware"
+	"material_todo_go/models"
+	"material_todo_go/utils"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// RequestAccountDeletion schedules the account for deletion after the grace period, confirmed by password
+func RequestAccountDeletion(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	var request struct {
+		Password string `json:"password"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil || request.Password == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Password is required"})
+		return
+	}
+
+	if !utils.CheckPasswordHash(request.Password, user.Password) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid password"})
+		return
+	}
+
+	deleteAt := time.Now().Add(config.AccountDeletionGracePeriod)
+	if err := database.DB.Model(&user).Update("deletion_scheduled_at", deleteAt).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to schedule account deletion"})
+		return
+	}
+
+	if err := mailer.SendTemplate(user.Email, mailer.TemplateAccountEvent, map[string]interface{}{
+		"FullName": user.FullName,
+		"Subject":  "Your account is scheduled for deletion",
+		"Message": fmt.Sprintf("Your account and all of its data will be permanently deleted on %s. Log in and cancel the deletion before then to keep it.",
+			deleteAt.Format("2006-01-02 15:04 MST")),
+	}); err != nil {
+		log.Printf("Failed to send deletion notice to %s: %v", user.Email, err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deletion_scheduled_at": deleteAt})
+}
+
+// CancelAccountDeletion keeps an account that is still within its grace period
+func CancelAccountDeletion(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	if user.DeletionScheduledAt == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Account is not scheduled for deletion"})
+		return
+	}
+
+	if err := database.DB.Model(&user).Update("deletion_scheduled_at", nil).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel account deletion"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// writeJSONFile adds an indented JSON document to the archive
+func writeJSONFile(archive *zip.Writer, name string, value interface{}) error {
+	w, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+// copyUploadedFile adds a file from the uploads directory to the archive under files/
+func copyUploadedFile(archive *zip.Writer, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	w, err := archive.Create("files/" + filepath.Base(path))
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(w, file)
+	return err
+}
+
+// ExportAccountData streams a zip archive with everything the user owns as JSON plus their uploaded files
+func ExportAccountData(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	var notes []models.Note
+	var taskGroups []models.TaskGroup
+	var tasks []models.Task
+	var memberships []models.TaskGroupMember
+	var workspaces []models.WorkspaceMember
+	var assignments []models.TaskAssignee
+	var comments []models.TaskComment
+	var notificationList []models.Notification
+	var sessions []models.Session
+	var identities []models.OAuthIdentity
+	var personalTokens []models.PersonalAccessToken
+	var auditEvents []models.AuditEvent
+
+	database.DB.Where("user_id = ?", user.ID).Find(&notes)
+	database.DB.Where("user_id = ?", user.ID).Find(&taskGroups)
+	database.DB.Where("user_id = ?", user.ID).Find(&tasks)
+	database.DB.Where("user_id = ?", user.ID).Find(&memberships)
+	database.DB.Where("user_id = ?", user.ID).Find(&workspaces)
+	database.DB.Where("user_id = ?", user.ID).Find(&assignments)
+	database.DB.Where("author_id = ?", user.ID).Find(&comments)
+	database.DB.Where("user_id = ?", user.ID).Find(&notificationList)
+	database.DB.Where("user_id = ?", user.ID).Find(&sessions)
+	database.DB.Where("user_id = ?", user.ID).Find(&identities)
+	database.DB.Where("user_id = ?", user.ID).Find(&personalTokens)
+	database.DB.Where("actor_id = ? OR subject_id = ?", user.ID, user.ID).Order("id").Find(&auditEvents)
+
+	profile := gin.H{
+		"id":                    user.ID,
+		"full_name":             user.FullName,
+		"email":                 user.Email,
+		"image":                 user.Image,
+		"email_verified":        user.EmailVerified,
+		"two_factor_enabled":    user.TOTPEnabled,
+		"deletion_scheduled_at": user.DeletionScheduledAt,
+	}
+
+	filename := fmt.Sprintf("account-export-%d-%s.zip", user.ID, time.Now().Format("20060102"))
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+
+	archive := zip.NewWriter(c.Writer)
+	files := []struct {
+		name  string
+		value interface{}
+	}{
+		{"profile.json", profile},
+		{"notes.json", notes},
+		{"task_groups.json", taskGroups},
+		{"tasks.json", tasks},
+		{"shared_task_groups.json", memberships},
+		{"workspaces.json", workspaces},
+		{"task_assignments.json", assignments},
+		{"task_comments.json", comments},
+		{"notifications.json", notificationList},
+		{"sessions.json", sessions},
+		{"linked_accounts.json", identities},
+		{"personal_access_tokens.json", personalTokens},
+		{"security_events.json", auditEvents},
+	}
+
+	// Headers are already sent, so failures can only be logged and end the archive early
+	for _, file := range files {
+		if err := writeJSONFile(archive, file.name, file.value); err != nil {
+			log.Printf("Failed to export %s for user %d: %v", file.name, user.ID, err)
+			archive.Close()
+			return
+		}
+	}
+
+	if user.Image != "" {
+		if err := copyUploadedFile(archive, user.Image); err != nil {
+			log.Printf("Failed to export avatar for user %d: %v", user.ID, err)
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		log.Printf("Failed to finish export for user %d: %v", user.ID, err)
+	}
+}
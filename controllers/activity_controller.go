@@ -0,0 +1,128 @@
+package controllers
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"log"
+	"material_todo_go/database"
+	"material_todo_go/middleware"
+	"material_todo_go/models"
+	"net/http"
+	"strconv"
+)
+
+// recordActivity appends an entry to the feed of a task group; failures are logged and never fail the request
+func recordActivity(c *gin.Context, taskGroupID uint, action string, taskID *uint, metadata map[string]interface{}) {
+	encoded := "{}"
+	if len(metadata) > 0 {
+		if value, err := json.Marshal(metadata); err == nil {
+			encoded = string(value)
+		}
+	}
+
+	activity := models.TaskGroupActivity{
+		TaskGroupID: taskGroupID,
+		ActorID:     middleware.CurrentUser(c).ID,
+		Action:      action,
+		TaskID:      taskID,
+		Metadata:    encoded,
+	}
+	if err := database.DB.Create(&activity).Error; err != nil {
+		log.Printf("Failed to record %s activity for task group %d: %v", action, taskGroupID, err)
+	}
+}
+
+// changedTaskGroupFields lists the settings that differ between two versions of a task group
+func changedTaskGroupFields(before, after models.TaskGroup) []string {
+	fields := []string{}
+	if before.Name != after.Name {
+		fields = append(fields, "name")
+	}
+	if before.Description != after.Description {
+		fields = append(fields, "description")
+	}
+	if before.IconData != after.IconData {
+		fields = append(fields, "icon_data")
+	}
+	if before.BackgroundColor != after.BackgroundColor {
+		fields = append(fields, "background_color")
+	}
+	if before.IconColor != after.IconColor {
+		fields = append(fields, "icon_color")
+	}
+	return fields
+}
+
+// changedTaskFields lists the fields that differ between two versions of a task, besides its group
+func changedTaskFields(before, after models.Task) []string {
+	fields := []string{}
+	if before.Title != after.Title {
+		fields = append(fields, "title")
+	}
+	if before.Description != after.Description {
+		fields = append(fields, "description")
+	}
+	if before.Status != after.Status {
+		fields = append(fields, "status")
+	}
+	if !before.StartDate.Equal(after.StartDate) {
+		fields = append(fields, "start_date")
+	}
+	if !before.FinishDate.Equal(after.FinishDate) {
+		fields = append(fields, "finish_date")
+	}
+	return fields
+}
+
+// GetTaskGroupActivity returns the feed of a task group, newest first. Pass next_cursor from a response
+// as cursor to get the following page; limit defaults to 20 and is at most 100.
+func GetTaskGroupActivity(c *gin.Context) {
+	taskGroup, ok := findTaskGroup(c, c.Param("id"), models.TaskGroupViewer)
+	if !ok {
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	query := database.DB.Preload("Actor").Where("task_group_id = ?", taskGroup.ID)
+	if cursor := c.Query("cursor"); cursor != "" {
+		cursorID, err := strconv.ParseUint(cursor, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+			return
+		}
+		query = query.Where("id < ?", cursorID)
+	}
+
+	var activities []models.TaskGroupActivity
+	if err := query.Order("id DESC").Limit(limit).Find(&activities).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve activity"})
+		return
+	}
+
+	response := []gin.H{}
+	for _, activity := range activities {
+		response = append(response, gin.H{
+			"id":     activity.ID,
+			"action": activity.Action,
+			"actor": gin.H{
+				"user_id":   activity.ActorID,
+				"full_name": activity.Actor.FullName,
+				"image":     activity.Actor.Image,
+			},
+			"task_id":    activity.TaskID,
+			"metadata":   json.RawMessage(activity.Metadata),
+			"created_at": activity.CreatedAt,
+		})
+	}
+
+	var nextCursor interface{}
+	if len(activities) == limit {
+		nextCursor = strconv.FormatUint(uint64(activities[len(activities)-1].ID), 10)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"activity": response, "next_cursor": nextCursor})
+}
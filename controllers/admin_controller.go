@@ -0,0 +1,219 @@
+package controllers
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"log"
+	"material_todo_go/audit"
+	"material_todo_go/database"
+	"material_todo_go/mailer"
+	"material_todo_go/middleware"
+	"material_todo_go/models"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// adminUserResponse is the user representation shown to admins, without secrets
+func adminUserResponse(user models.User) gin.H {
+	return gin.H{
+		"id":                      user.ID,
+		"full_name":               user.FullName,
+		"email":                   user.Email,
+		"image":                   user.Image,
+		"role":                    user.Role,
+		"disabled":                user.Disabled,
+		"email_verified":          user.EmailVerified,
+		"two_factor_enabled":      user.TOTPEnabled,
+		"password_reset_required": user.PasswordResetRequired,
+		"deletion_scheduled_at":   user.DeletionScheduledAt,
+	}
+}
+
+// pagination reads the 1-based page and page_size query parameters, page_size being at most 100
+func pagination(c *gin.Context) (int, int) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	return page, pageSize
+}
+
+// findAdminTargetUser loads the user from the :id parameter, responding 404 when missing
+func findAdminTargetUser(c *gin.Context) (models.User, bool) {
+	var user models.User
+	if err := database.DB.First(&user, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return user, false
+	}
+	return user, true
+}
+
+// AdminGetUsers lists users, optionally filtered by a search on name or email, with pagination
+func AdminGetUsers(c *gin.Context) {
+	page, pageSize := pagination(c)
+
+	query := database.DB.Model(&models.User{})
+	if search := strings.TrimSpace(c.Query("q")); search != "" {
+		like := "%" + strings.ToLower(search) + "%"
+		query = query.Where("LOWER(email) LIKE ? OR LOWER(full_name) LIKE ?", like, like)
+	}
+	if role := c.Query("role"); role != "" {
+		query = query.Where("role = ?", role)
+	}
+	if disabled := c.Query("disabled"); disabled != "" {
+		query = query.Where("disabled = ?", disabled == "true")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve users"})
+		return
+	}
+
+	var users []models.User
+	if err := query.Order("id").Offset((page - 1) * pageSize).Limit(pageSize).Find(&users).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve users"})
+		return
+	}
+
+	response := []gin.H{}
+	for _, user := range users {
+		response = append(response, adminUserResponse(user))
+	}
+
+	c.JSON(http.StatusOK, gin.H{"users": response, "total": total, "page": page, "page_size": pageSize})
+}
+
+// AdminGetUser returns a user with the number of notes, task groups and tasks they own
+func AdminGetUser(c *gin.Context) {
+	user, ok := findAdminTargetUser(c)
+	if !ok {
+		return
+	}
+
+	var notes, taskGroups, tasks int64
+	database.DB.Model(&models.Note{}).Where("user_id = ?", user.ID).Count(&notes)
+	database.DB.Model(&models.TaskGroup{}).Where("user_id = ?", user.ID).Count(&taskGroups)
+	database.DB.Model(&models.Task{}).Where("user_id = ?", user.ID).Count(&tasks)
+
+	response := adminUserResponse(user)
+	response["counts"] = gin.H{
+		"notes":       notes,
+		"task_groups": taskGroups,
+		"tasks":       tasks,
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// setUserDisabled disables or enables the target account; disabling also signs it out everywhere
+func setUserDisabled(c *gin.Context, disabled bool) {
+	user, ok := findAdminTargetUser(c)
+	if !ok {
+		return
+	}
+
+	if user.ID == middleware.CurrentUser(c).ID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot change your own account status"})
+		return
+	}
+
+	if err := database.DB.Model(&user).Update("disabled", disabled).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
+		return
+	}
+
+	event := audit.EventAdminUserEnabled
+	if disabled {
+		event = audit.EventAdminUserDisabled
+		if err := invalidateUserTokens(user.ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+			return
+		}
+	}
+
+	audit.Record(c, audit.Entry{Event: event, ActorID: middleware.CurrentUser(c).ID, SubjectID: user.ID, Email: user.Email})
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// AdminDisableUser blocks a user from logging in and revokes their sessions
+func AdminDisableUser(c *gin.Context) {
+	setUserDisabled(c, true)
+}
+
+// AdminEnableUser lifts a previous AdminDisableUser
+func AdminEnableUser(c *gin.Context) {
+	setUserDisabled(c, false)
+}
+
+// AdminForcePasswordReset signs the user out, blocks password logins and emails them a reset code
+func AdminForcePasswordReset(c *gin.Context) {
+	user, ok := findAdminTargetUser(c)
+	if !ok {
+		return
+	}
+
+	if err := database.DB.Model(&user).Update("password_reset_required", true).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
+		return
+	}
+
+	if err := invalidateUserTokens(user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+		return
+	}
+
+	if err := sendResetCode(user); err != nil {
+		log.Printf("Failed to send forced reset code to %s: %v", user.Email, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send reset code"})
+		return
+	}
+
+	if err := mailer.SendTemplate(user.Email, mailer.TemplateAccountEvent, map[string]interface{}{
+		"FullName": user.FullName,
+		"Subject":  "Password reset required",
+		"Message":  "An administrator requires you to choose a new password. Use the reset code we just sent you to set it.",
+	}); err != nil {
+		log.Printf("Failed to notify %s about forced reset: %v", user.Email, err)
+	}
+
+	audit.Record(c, audit.Entry{Event: audit.EventAdminPasswordForced, ActorID: middleware.CurrentUser(c).ID, SubjectID: user.ID, Email: user.Email})
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// AdminUpdateUserRole changes the role of a user
+func AdminUpdateUserRole(c *gin.Context) {
+	user, ok := findAdminTargetUser(c)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		Role string `json:"role"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil || (request.Role != models.RoleUser && request.Role != models.RoleAdmin) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Role must be %q or %q", models.RoleUser, models.RoleAdmin)})
+		return
+	}
+
+	if user.ID == middleware.CurrentUser(c).ID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot change your own role"})
+		return
+	}
+
+	if err := database.DB.Model(&user).Update("role", request.Role).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
+		return
+	}
+
+	audit.Record(c, audit.Entry{Event: audit.EventAdminRoleChanged, ActorID: middleware.CurrentUser(c).ID, SubjectID: user.ID, Email: user.Email,
+		Metadata: map[string]interface{}{"from": user.Role, "to": request.Role}})
+
+	c.JSON(http.StatusOK, gin.H{})
+}
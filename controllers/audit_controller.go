@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"material_todo_go/audit"
+	"material_todo_go/database"
+	"material_todo_go/middleware"
+	"material_todo_go/models"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// auditEventResponse renders an event with its metadata as a JSON object
+func auditEventResponse(event models.AuditEvent) gin.H {
+	return gin.H{
+		"id":         event.ID,
+		"event":      event.Event,
+		"actor_id":   event.ActorID,
+		"subject_id": event.SubjectID,
+		"email":      event.Email,
+		"ip":         event.IP,
+		"user_agent": event.UserAgent,
+		"metadata":   json.RawMessage(event.Metadata),
+		"created_at": event.CreatedAt,
+	}
+}
+
+// filterAuditEvents applies the event, from and to query parameters
+func filterAuditEvents(c *gin.Context, query *gorm.DB) (*gorm.DB, bool) {
+	if event := c.Query("event"); event != "" {
+		query = query.Where("event = ?", event)
+	}
+
+	for param, condition := range map[string]string{"from": "created_at >= ?", "to": "created_at < ?"} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + param + " date, expected RFC 3339"})
+			return nil, false
+		}
+		query = query.Where(condition, t)
+	}
+	return query, true
+}
+
+// listAuditEvents responds with one page of the query, newest first
+func listAuditEvents(c *gin.Context, query *gorm.DB) {
+	page, pageSize := pagination(c)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve events"})
+		return
+	}
+
+	var events []models.AuditEvent
+	if err := query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&events).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve events"})
+		return
+	}
+
+	response := []gin.H{}
+	for _, event := range events {
+		response = append(response, auditEventResponse(event))
+	}
+
+	c.JSON(http.StatusOK, gin.H{"events": response, "total": total, "page": page, "page_size": pageSize})
+}
+
+// GetAuditEvents lists the security events performed by or on the authenticated user
+func GetAuditEvents(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	query := database.DB.Model(&models.AuditEvent{}).Where("actor_id = ? OR subject_id = ?", user.ID, user.ID)
+	query, ok := filterAuditEvents(c, query)
+	if !ok {
+		return
+	}
+	listAuditEvents(c, query)
+}
+
+// AdminGetAuditEvents lists all security events, filtered by event, actor_id, subject_id, email, ip, from and to
+func AdminGetAuditEvents(c *gin.Context) {
+	query := database.DB.Model(&models.AuditEvent{})
+	for _, column := range []string{"actor_id", "subject_id", "email", "ip"} {
+		if value := c.Query(column); value != "" {
+			query = query.Where(column+" = ?", strings.ToLower(value))
+		}
+	}
+
+	query, ok := filterAuditEvents(c, query)
+	if !ok {
+		return
+	}
+	listAuditEvents(c, query)
+}
+
+// AdminVerifyAuditLog checks that no event has been altered or removed
+func AdminVerifyAuditLog(c *gin.Context) {
+	brokenID, err := audit.Verify()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify audit log"})
+		return
+	}
+
+	if brokenID != 0 {
+		c.JSON(http.StatusOK, gin.H{"intact": false, "broken_at": brokenID})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"intact": true})
+}
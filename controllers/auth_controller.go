@@ -1,14 +1,21 @@
 package controllers
 
 import (
-	"errors"
+	"crypto/subtle"
 	"fmt"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"log"
+	"material_todo_go/audit"
+	"material_todo_go/config"
 	"material_todo_go/database"
+	"material_todo_go/limiter"
+	"material_todo_go/mailer"
+	"material_todo_go/middleware"
 	"material_todo_go/models"
 	"material_todo_go/utils"
-	"math/rand"
 	"net/http"
+	"net/mail"
 	_ "os"
 	"path/filepath"
 	"strings"
@@ -43,18 +50,88 @@ func Login(c *gin.Context) {
 		return
 	}
 
+	// Back off repeated failures per account and per client IP
+	if !checkRateLimit(c, limiter.Accounts, loginAccountKey(request["email"])) ||
+		!checkRateLimit(c, limiter.IPs, loginIPKey(c.ClientIP())) {
+		return
+	}
+
 	var user models.User
-	database.DB.Where("email = ?", request["email"]).First(&user)
+	database.DB.Where("LOWER(email) = ?", normalizeEmail(request["email"])).First(&user)
 
 	// Check if user exists and password is correct
 	if user.ID == 0 || !utils.CheckPasswordHash(request["password"], user.Password) {
+		recordLoginFailure(c, user, request["email"])
+		audit.Record(c, audit.Entry{Event: audit.EventLoginFailed, SubjectID: user.ID, Email: request["email"],
+			Metadata: map[string]interface{}{"reason": "invalid_credentials"}})
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 		return
 	}
 
-	// Generate JWT token
-	token, _ := utils.GenerateJWT(user.Email)
-	c.JSON(http.StatusOK, gin.H{"token": token})
+	limiter.Accounts.Reset(loginAccountKey(user.Email))
+
+	// Upgrade the stored hash while the plain password is at hand
+	if utils.NeedsRehash(user.Password) {
+		if hashedPassword, err := utils.HashPassword(request["password"]); err == nil {
+			database.DB.Model(&user).Update("password", hashedPassword)
+		}
+	}
+
+	completeLogin(c, user)
+}
+
+// completeLogin answers a successful first factor with a two-factor challenge or with tokens,
+// unless the account may not sign in at all
+func completeLogin(c *gin.Context, user models.User) {
+	if !signInAllowed(c, user) {
+		return
+	}
+
+	// Accounts with two-factor authentication get a challenge instead of tokens
+	if user.TOTPEnabled {
+		challenge, err := utils.GenerateChallengeJWT(user.Email)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"two_factor_required": true, "challenge_token": challenge})
+		return
+	}
+
+	loginWithNewSession(c, user)
+}
+
+// signInAllowed refuses disabled accounts, accounts waiting for a password reset and, depending on the
+// policy, unverified ones. It responds and records the failure itself.
+func signInAllowed(c *gin.Context, user models.User) bool {
+	if user.Disabled {
+		audit.Record(c, audit.Entry{Event: audit.EventLoginFailed, SubjectID: user.ID, Email: user.Email,
+			Metadata: map[string]interface{}{"reason": "disabled"}})
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account is disabled"})
+		return false
+	}
+
+	if user.PasswordResetRequired {
+		audit.Record(c, audit.Entry{Event: audit.EventLoginFailed, SubjectID: user.ID, Email: user.Email,
+			Metadata: map[string]interface{}{"reason": "password_reset_required"}})
+		c.JSON(http.StatusForbidden, gin.H{"error": "Password reset required"})
+		return false
+	}
+
+	// Refuse unverified accounts unless the policy allows them in
+	if !user.EmailVerified && config.UnverifiedLoginPolicy == "deny" {
+		audit.Record(c, audit.Entry{Event: audit.EventLoginFailed, SubjectID: user.ID, Email: user.Email,
+			Metadata: map[string]interface{}{"reason": "unverified"}})
+		c.JSON(http.StatusForbidden, gin.H{"error": "Email address is not verified"})
+		return false
+	}
+
+	return true
+}
+
+// normalizeEmail returns the form addresses are stored and looked up in
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
 }
 
 func Signup(c *gin.Context) {
@@ -69,6 +146,26 @@ func Signup(c *gin.Context) {
 		return
 	}
 
+	// Accounts are keyed by the bare, lowercased address
+	address, err := mail.ParseAddress(strings.TrimSpace(email))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid email"})
+		return
+	}
+	email = normalizeEmail(address.Address)
+
+	var existing int64
+	database.DB.Model(&models.User{}).Where("LOWER(email) = ?", email).Count(&existing)
+	if existing > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Email is already in use"})
+		return
+	}
+
+	if err := utils.ValidatePassword(password); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	// Handle file upload (optional)
 	filePath := "uploads/default_avatar.png" // Default avatar
 	file, err := c.FormFile("image")
@@ -103,15 +200,26 @@ func Signup(c *gin.Context) {
 		return
 	}
 
+	if err := database.CreatePersonalWorkspace(&user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create workspace"})
+		return
+	}
+
+	audit.Record(c, audit.Entry{Event: audit.EventSignup, ActorID: user.ID, SubjectID: user.ID, Email: user.Email})
+
+	// Send the verification email; the user can request a new one if delivery fails
+	if err := sendVerificationEmail(user); err != nil {
+		log.Printf("Failed to send verification email to %s: %v", user.Email, err)
+	}
+
 	// Return success response
 	c.JSON(http.StatusCreated, gin.H{})
 }
 
-// GenerateRandomCode generates a 6-digit random code
-func GenerateRandomCode() string {
-	rand.Seed(time.Now().UnixNano()) // Ensure randomness
-	return fmt.Sprintf("%06d", rand.Intn(1000000))
-}
+const (
+	resetCodeTTL         = 15 * time.Minute
+	maxResetCodeAttempts = 5
+)
 
 func SendResetCode(c *gin.Context) {
 	var request struct {
@@ -123,80 +231,144 @@ func SendResetCode(c *gin.Context) {
 		return
 	}
 
+	// Limit how many codes can be requested per account and per client IP
+	resetAccountKey := "reset:account:" + strings.ToLower(request.Email)
+	resetIPKey := "reset:ip:" + c.ClientIP()
+	if !checkRateLimit(c, limiter.ResetCodes, resetAccountKey) || !checkRateLimit(c, limiter.IPs, resetIPKey) {
+		return
+	}
+	// Every request counts, successful or not
+	limiter.ResetCodes.Fail(resetAccountKey)
+	limiter.IPs.Fail(resetIPKey)
+
 	// Check if user exists
 	var user models.User
-	if err := database.DB.Where("email = ?", request.Email).First(&user).Error; err != nil {
+	if err := database.DB.Where("LOWER(email) = ?", normalizeEmail(request.Email)).First(&user).Error; err != nil {
+		audit.Record(c, audit.Entry{Event: audit.EventResetCodeSent, Email: request.Email,
+			Metadata: map[string]interface{}{"result": "unknown_email"}})
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
 
+	if err := sendResetCode(user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send reset code"})
+		return
+	}
+
+	audit.Record(c, audit.Entry{Event: audit.EventResetCodeSent, SubjectID: user.ID, Email: user.Email,
+		Metadata: map[string]interface{}{"result": "sent"}})
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// sendResetCode stores a new reset code for the user, replacing older ones, and emails it
+func sendResetCode(user models.User) error {
 	// Generate a 6-digit reset code
-	resetCode := GenerateRandomCode()
+	resetCode, err := utils.GenerateRandomCode()
+	if err != nil {
+		return err
+	}
 
-	// Store the reset code in the database (optional)
-	// You may store this in a `password_resets` table if needed.
+	// Guessing is bounded by maxResetCodeAttempts, so a fast hash is enough and keeps this endpoint cheap
+	codeHash := utils.HashToken(resetCode)
 
-	c.JSON(http.StatusOK, gin.H{"code": resetCode})
+	// Only the most recent code is valid, invalidate the previous ones
+	database.DB.Model(&models.PasswordReset{}).Where("user_id = ? AND used = ?", user.ID, false).Update("used", true)
+
+	reset := models.PasswordReset{
+		UserID:    user.ID,
+		CodeHash:  codeHash,
+		ExpiresAt: time.Now().Add(resetCodeTTL),
+	}
+	if err := database.DB.Create(&reset).Error; err != nil {
+		return err
+	}
+
+	// Email the code, it is never returned to the caller
+	return mailer.SendTemplate(user.Email, mailer.TemplateResetCode, map[string]interface{}{
+		"FullName":  user.FullName,
+		"Code":      resetCode,
+		"ExpiresIn": fmt.Sprintf("%d minutes", int(resetCodeTTL.Minutes())),
+	})
 }
 
 func ResetPassword(c *gin.Context) {
 	var request struct {
 		Email    string `json:"email"`
+		Code     string `json:"code"`
 		Password string `json:"password"`
 	}
 
-	if err := c.ShouldBindJSON(&request); err != nil || request.Email == "" || request.Password == "" {
+	if err := c.ShouldBindJSON(&request); err != nil || request.Email == "" || request.Code == "" || request.Password == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
 		return
 	}
 
+	if err := utils.ValidatePassword(request.Password); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	// Check if user exists
 	var user models.User
-	if err := database.DB.Where("email = ?", request.Email).First(&user).Error; err != nil {
+	if err := database.DB.Where("LOWER(email) = ?", normalizeEmail(request.Email)).First(&user).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
 
-	// Hash new password
-	hashedPassword, err := utils.HashPassword(request.Password)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+	// Find the latest active reset code for this user
+	var reset models.PasswordReset
+	if err := database.DB.Where("user_id = ? AND used = ? AND expires_at > ?", user.ID, false, time.Now()).
+		Order("created_at DESC").First(&reset).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired code"})
 		return
 	}
 
-	// Update password
-	if err := database.DB.Model(&models.User{}).Where("email = ?", request.Email).Update("password", hashedPassword).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update password"})
+	// Count the attempt before checking it, so concurrent guesses cannot exceed the limit
+	result := database.DB.Model(&models.PasswordReset{}).Where("id = ? AND attempts < ?", reset.ID, maxResetCodeAttempts).
+		Update("attempts", gorm.Expr("attempts + 1"))
+	if result.Error != nil || result.RowsAffected == 0 {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many attempts, request a new code"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{})
-}
-
-// getAuthenticatedUserID extracts user ID from the JWT token
-func getAuthenticatedUserID(c *gin.Context) (uint, error) {
-	authHeader := c.GetHeader("Authorization")
-	if authHeader == "" {
-		return 0, errors.New("Authorization token required")
+	if subtle.ConstantTimeCompare([]byte(utils.HashToken(request.Code)), []byte(reset.CodeHash)) != 1 {
+		audit.Record(c, audit.Entry{Event: audit.EventPasswordResetFailed, SubjectID: user.ID, Email: user.Email,
+			Metadata: map[string]interface{}{"reason": "invalid_code"}})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired code"})
+		return
 	}
 
-	parts := strings.Split(authHeader, " ")
-	if len(parts) != 2 || parts[0] != "Bearer" {
-		return 0, errors.New("Invalid Authorization header format")
+	// Mark the code as used; the condition guarantees a code can only be consumed once
+	result = database.DB.Model(&models.PasswordReset{}).Where("id = ? AND used = ?", reset.ID, false).Update("used", true)
+	if result.Error != nil || result.RowsAffected == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired code"})
+		return
 	}
 
-	email, err := utils.ParseJWT(parts[1])
+	// Hash new password
+	hashedPassword, err := utils.HashPassword(request.Password)
 	if err != nil {
-		return 0, errors.New("Invalid token")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
 	}
 
-	// Retrieve user ID based on email
-	var user models.User
-	if err := database.DB.Where("email = ?", email).First(&user).Error; err != nil {
-		return 0, errors.New("User not found")
+	// Update password and clear a reset forced by an admin
+	if err := database.DB.Model(&models.User{}).Where("id = ?", user.ID).
+		Updates(map[string]interface{}{"password": hashedPassword, "password_reset_required": false}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update password"})
+		return
 	}
 
-	return user.ID, nil
+	// Sessions started with the old password must not survive the change
+	if err := invalidateUserTokens(user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke existing sessions"})
+		return
+	}
+
+	audit.Record(c, audit.Entry{Event: audit.EventPasswordReset, ActorID: user.ID, SubjectID: user.ID, Email: user.Email})
+
+	c.JSON(http.StatusOK, gin.H{})
 }
 
 func ValidateToken(c *gin.Context) {
@@ -206,11 +378,7 @@ func ValidateToken(c *gin.Context) {
 		token = c.Query("token") // Optional: allows token in query params
 	}
 	if token == "" {
-		authHeader := c.GetHeader("Authorization")
-		parts := strings.Split(authHeader, " ")
-		if len(parts) == 2 && parts[0] == "Bearer" {
-			token = parts[1]
-		}
+		token = middleware.BearerToken(c)
 	}
 
 	if token == "" {
@@ -221,6 +389,7 @@ func ValidateToken(c *gin.Context) {
 	// Validate the JWT token
 	_, err := utils.ParseJWT(token)
 	if err != nil {
+		audit.Record(c, audit.Entry{Event: audit.EventTokenRejected, Metadata: map[string]interface{}{"endpoint": "validate-token"}})
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
 		return
 	}
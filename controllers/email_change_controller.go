@@ -0,0 +1,140 @@
+package controllers
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"log"
+	"material_todo_go/audit"
+	"material_todo_go/database"
+	"material_todo_go/mailer"
+	"material_todo_go/middleware"
+	"material_todo_go/models"
+	"material_todo_go/utils"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+const emailChangeTokenTTL = 24 * time.Hour
+
+// RequestEmailChange emails a confirmation token to the new address and warns the current one
+func RequestEmailChange(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	var request struct {
+		Password string `json:"password"`
+		NewEmail string `json:"new_email"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil || request.Password == "" || request.NewEmail == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Password and new email are required"})
+		return
+	}
+
+	// Only the bare address is kept, so "Name <a@b.c>" cannot slip past the uniqueness check
+	address, err := mail.ParseAddress(strings.TrimSpace(request.NewEmail))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid email"})
+		return
+	}
+	newEmail := normalizeEmail(address.Address)
+
+	if !utils.CheckPasswordHash(request.Password, user.Password) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid password"})
+		return
+	}
+
+	if strings.EqualFold(newEmail, user.Email) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "New email is the same as the current one"})
+		return
+	}
+
+	var existing int64
+	database.DB.Model(&models.User{}).Where("LOWER(email) = LOWER(?)", newEmail).Count(&existing)
+	if existing > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Email is already in use"})
+		return
+	}
+
+	token, err := utils.GenerateToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	// Only the latest request can be confirmed
+	database.DB.Where("user_id = ?", user.ID).Delete(&models.EmailChange{})
+
+	change := models.EmailChange{
+		UserID:    user.ID,
+		NewEmail:  newEmail,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: time.Now().Add(emailChangeTokenTTL),
+	}
+	if err := database.DB.Create(&change).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store email change"})
+		return
+	}
+
+	if err := mailer.SendTemplate(newEmail, mailer.TemplateConfirmEmailChange, map[string]interface{}{
+		"FullName":  user.FullName,
+		"Token":     token,
+		"ExpiresIn": fmt.Sprintf("%d hours", int(emailChangeTokenTTL.Hours())),
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send confirmation email"})
+		return
+	}
+
+	if err := mailer.SendTemplate(user.Email, mailer.TemplateAccountEvent, map[string]interface{}{
+		"FullName": user.FullName,
+		"Subject":  "Email change requested",
+		"Message":  fmt.Sprintf("Someone asked to change the email address of your account to %s. Nothing changes until the new address is confirmed. If this was not you, change your password now.", newEmail),
+	}); err != nil {
+		log.Printf("Failed to notify %s about email change: %v", user.Email, err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// ConfirmEmailChange switches the account to the new address and signs out every session
+func ConfirmEmailChange(c *gin.Context) {
+	var request struct {
+		Token string `json:"token"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil || request.Token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Token is required"})
+		return
+	}
+
+	var change models.EmailChange
+	if err := database.DB.Where("token_hash = ? AND expires_at > ?", utils.HashToken(request.Token), time.Now()).
+		First(&change).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired token"})
+		return
+	}
+
+	var existing int64
+	database.DB.Model(&models.User{}).Where("LOWER(email) = LOWER(?)", change.NewEmail).Count(&existing)
+	if existing > 0 {
+		database.DB.Delete(&change)
+		c.JSON(http.StatusConflict, gin.H{"error": "Email is already in use"})
+		return
+	}
+
+	if err := database.DB.Model(&models.User{}).Where("id = ?", change.UserID).
+		Updates(map[string]interface{}{"email": change.NewEmail, "email_verified": true}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change email"})
+		return
+	}
+	database.DB.Where("user_id = ?", change.UserID).Delete(&models.EmailChange{})
+
+	// Access tokens are keyed by email, so none of the existing ones may survive
+	if err := invalidateUserTokens(change.UserID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke existing sessions"})
+		return
+	}
+
+	audit.Record(c, audit.Entry{Event: audit.EventEmailChanged, ActorID: change.UserID, SubjectID: change.UserID, Email: change.NewEmail})
+
+	c.JSON(http.StatusOK, gin.H{})
+}
@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"material_todo_go/database"
+	"material_todo_go/mailer"
+	"material_todo_go/models"
+	"material_todo_go/utils"
+	"net/http"
+	"time"
+)
+
+const (
+	verificationTokenTTL       = 24 * time.Hour
+	verificationResendInterval = time.Minute
+	verificationHourlyLimit    = 5
+)
+
+// sendVerificationEmail issues a new verification token for the user and emails it
+func sendVerificationEmail(user models.User) error {
+	token, err := utils.GenerateToken()
+	if err != nil {
+		return err
+	}
+
+	verification := models.EmailVerification{
+		UserID:    user.ID,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: time.Now().Add(verificationTokenTTL),
+	}
+	if err := database.DB.Create(&verification).Error; err != nil {
+		return err
+	}
+
+	return mailer.SendTemplate(user.Email, mailer.TemplateVerifyEmail, map[string]interface{}{
+		"FullName":  user.FullName,
+		"Token":     token,
+		"ExpiresIn": fmt.Sprintf("%d hours", int(verificationTokenTTL.Hours())),
+	})
+}
+
+// VerifyEmail marks the user's email as verified using the emailed token
+func VerifyEmail(c *gin.Context) {
+	var request struct {
+		Token string `json:"token"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil || request.Token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Token is required"})
+		return
+	}
+
+	var verification models.EmailVerification
+	if err := database.DB.Where("token_hash = ? AND expires_at > ?", utils.HashToken(request.Token), time.Now()).
+		First(&verification).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired token"})
+		return
+	}
+
+	if err := database.DB.Model(&models.User{}).Where("id = ?", verification.UserID).Update("email_verified", true).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email"})
+		return
+	}
+
+	// Tokens are single-use, drop every outstanding token for this user
+	database.DB.Where("user_id = ?", verification.UserID).Delete(&models.EmailVerification{})
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// ResendVerification emails a fresh verification token, throttled per account
+func ResendVerification(c *gin.Context) {
+	var request struct {
+		Email string `json:"email"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil || request.Email == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid email"})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.Where("email = ?", request.Email).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if user.EmailVerified {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Email is already verified"})
+		return
+	}
+
+	// Throttle: one email per interval and a limited number per hour
+	var last models.EmailVerification
+	if err := database.DB.Where("user_id = ?", user.ID).Order("created_at DESC").First(&last).Error; err == nil {
+		if wait := time.Until(last.CreatedAt.Add(verificationResendInterval)); wait > 0 {
+			c.Header("Retry-After", fmt.Sprintf("%d", int(wait.Seconds())+1))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Please wait before requesting another email"})
+			return
+		}
+	}
+
+	var sentLastHour int64
+	database.DB.Model(&models.EmailVerification{}).Where("user_id = ? AND created_at > ?", user.ID, time.Now().Add(-time.Hour)).Count(&sentLastHour)
+	if sentLastHour >= verificationHourlyLimit {
+		c.Header("Retry-After", fmt.Sprintf("%d", int(time.Hour.Seconds())))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many verification emails requested"})
+		return
+	}
+
+	if err := sendVerificationEmail(user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send verification email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
@@ -0,0 +1,13 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+	"material_todo_go/utils"
+	"net/http"
+)
+
+// GetJWKS publishes the public keys other services use to verify tokens issued by this backend
+func GetJWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, utils.JWKS())
+}
@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"log"
+	"material_todo_go/database"
+	"material_todo_go/limiter"
+	"material_todo_go/mailer"
+	"material_todo_go/models"
+	"material_todo_go/utils"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const unlockTokenTTL = 24 * time.Hour
+
+func loginAccountKey(email string) string {
+	return "login:account:" + strings.ToLower(email)
+}
+
+func loginIPKey(ip string) string {
+	return "login:ip:" + ip
+}
+
+// checkRateLimit responds with 429 and a Retry-After header when the key must still wait
+func checkRateLimit(c *gin.Context, l *limiter.Limiter, key string) bool {
+	wait, err := l.Check(key)
+	if err != nil {
+		log.Printf("Failed to check rate limit for %s: %v", key, err)
+		return true
+	}
+
+	if wait > 0 {
+		c.Header("Retry-After", limiter.RetryAfter(wait))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many attempts, try again later"})
+		return false
+	}
+	return true
+}
+
+// recordLoginFailure counts a failed login for the account and the IP and emails an unlock link on lockout
+func recordLoginFailure(c *gin.Context, user models.User, email string) {
+	if _, wait, err := limiter.IPs.Fail(loginIPKey(c.ClientIP())); err == nil && wait > 0 {
+		c.Header("Retry-After", limiter.RetryAfter(wait))
+	}
+
+	failures, wait, err := limiter.Accounts.Fail(loginAccountKey(email))
+	if err != nil {
+		log.Printf("Failed to record login failure: %v", err)
+		return
+	}
+	if wait > 0 {
+		c.Header("Retry-After", limiter.RetryAfter(wait))
+	}
+
+	// Email the owner exactly once, when the failure crosses the lockout threshold
+	if user.ID != 0 && failures == limiter.Accounts.Policy.LockoutAfter {
+		if err := sendUnlockEmail(user); err != nil {
+			log.Printf("Failed to send unlock email to %s: %v", user.Email, err)
+		}
+	}
+}
+
+// sendUnlockEmail issues an unlock token for a locked account and emails it
+func sendUnlockEmail(user models.User) error {
+	token, err := utils.GenerateToken()
+	if err != nil {
+		return err
+	}
+
+	unlock := models.AccountUnlock{
+		UserID:    user.ID,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: time.Now().Add(unlockTokenTTL),
+	}
+	if err := database.DB.Create(&unlock).Error; err != nil {
+		return err
+	}
+
+	return mailer.SendTemplate(user.Email, mailer.TemplateUnlockAccount, map[string]interface{}{
+		"FullName":  user.FullName,
+		"Token":     token,
+		"LockedFor": fmt.Sprintf("%d minutes", int(limiter.Accounts.Policy.LockoutDuration.Minutes())),
+	})
+}
+
+// UnlockAccount lifts a lockout early using the emailed token
+func UnlockAccount(c *gin.Context) {
+	var request struct {
+		Token string `json:"token"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil || request.Token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Token is required"})
+		return
+	}
+
+	var unlock models.AccountUnlock
+	if err := database.DB.Where("token_hash = ? AND expires_at > ?", utils.HashToken(request.Token), time.Now()).
+		First(&unlock).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired token"})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, unlock.UserID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if err := limiter.Accounts.Reset(loginAccountKey(user.Email)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlock account"})
+		return
+	}
+
+	database.DB.Where("user_id = ?", user.ID).Delete(&models.AccountUnlock{})
+
+	c.JSON(http.StatusOK, gin.H{})
+}
@@ -1,17 +1,38 @@
 package controllers
 
 import (
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"material_todo_go/database"
+	"material_todo_go/middleware"
 	"material_todo_go/models"
+	"material_todo_go/notifications"
 	"net/http"
 )
 
-// CreateNote - Adds a new note for the authenticated user
+// findWorkspaceNote loads a note of the active workspace. Unless the user is a workspace admin,
+// only notes they wrote can be modified.
+func findWorkspaceNote(c *gin.Context, modify bool) (models.Note, bool) {
+	var note models.Note
+	if err := database.DB.Where("id = ? AND workspace_id = ?", c.Param("id"), middleware.CurrentWorkspace(c).ID).First(&note).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
+		return note, false
+	}
+
+	if modify && note.UserID != middleware.CurrentUser(c).ID &&
+		!middleware.WorkspaceRoleAtLeast(middleware.CurrentWorkspaceRole(c), models.WorkspaceRoleAdmin) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the author or a workspace admin can change this note"})
+		return note, false
+	}
+	return note, true
+}
+
+// CreateNote - Adds a new note for the authenticated user in the active workspace
 func CreateNote(c *gin.Context) {
-	userID, err := getAuthenticatedUserID(c)
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	userID := middleware.CurrentUser(c).ID
+
+	if !middleware.WorkspaceRoleAtLeast(middleware.CurrentWorkspaceRole(c), models.WorkspaceRoleMember) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Viewers cannot create notes"})
 		return
 	}
 
@@ -21,25 +42,24 @@ func CreateNote(c *gin.Context) {
 		return
 	}
 
+	note.ID = 0
 	note.UserID = userID
+	note.WorkspaceID = middleware.CurrentWorkspace(c).ID
 	if err := database.DB.Create(&note).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create note"})
 		return
 	}
 
+	notifications.SendMentions(notifications.Mentions(note.Description), canAccessWorkspace(note.WorkspaceID), middleware.CurrentUser(c),
+		fmt.Sprintf("note %q", note.Title), notifications.EntityNote, note.ID)
+
 	c.JSON(http.StatusCreated, gin.H{})
 }
 
-// GetAllNotes - Retrieves all notes for the authenticated user
+// GetAllNotes - Retrieves all notes of the active workspace
 func GetAllNotes(c *gin.Context) {
-	userID, err := getAuthenticatedUserID(c)
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
-		return
-	}
-
 	var notes []models.Note
-	result := database.DB.Where("user_id = ?", userID).Find(&notes)
+	result := database.DB.Where("workspace_id = ?", middleware.CurrentWorkspace(c).ID).Find(&notes)
 
 	if result.Error != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve notes"})
@@ -55,34 +75,20 @@ func GetAllNotes(c *gin.Context) {
 	c.JSON(http.StatusOK, notes)
 }
 
-// GetNoteByID - Retrieves a single note by ID for the authenticated user
+// GetNoteByID - Retrieves a single note by ID from the active workspace
 func GetNoteByID(c *gin.Context) {
-	userID, err := getAuthenticatedUserID(c)
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
-		return
-	}
-
-	var note models.Note
-	if err := database.DB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&note).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
+	note, ok := findWorkspaceNote(c, false)
+	if !ok {
 		return
 	}
 
 	c.JSON(http.StatusOK, note)
 }
 
-// UpdateNote - Updates an existing note for the authenticated user
+// UpdateNote - Updates an existing note of the active workspace
 func UpdateNote(c *gin.Context) {
-	userID, err := getAuthenticatedUserID(c)
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
-		return
-	}
-
-	var note models.Note
-	if err := database.DB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&note).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
+	note, ok := findWorkspaceNote(c, true)
+	if !ok {
 		return
 	}
 
@@ -96,6 +102,7 @@ func UpdateNote(c *gin.Context) {
 		return
 	}
 
+	previousDescription := note.Description
 	note.Title = updateData.Title
 	note.Description = updateData.Description
 
@@ -104,18 +111,20 @@ func UpdateNote(c *gin.Context) {
 		return
 	}
 
+	notifications.SendMentions(notifications.NewMentions(previousDescription, note.Description), canAccessWorkspace(note.WorkspaceID), middleware.CurrentUser(c),
+		fmt.Sprintf("note %q", note.Title), notifications.EntityNote, note.ID)
+
 	c.JSON(http.StatusOK, gin.H{})
 }
 
-// DeleteNote - Deletes a note for the authenticated user
+// DeleteNote - Deletes a note of the active workspace
 func DeleteNote(c *gin.Context) {
-	userID, err := getAuthenticatedUserID(c)
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	note, ok := findWorkspaceNote(c, true)
+	if !ok {
 		return
 	}
 
-	if err := database.DB.Where("id = ? AND user_id = ?", c.Param("id"), userID).Delete(&models.Note{}).Error; err != nil {
+	if err := database.DB.Delete(&note).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete note"})
 		return
 	}
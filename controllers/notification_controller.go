@@ -0,0 +1,186 @@
+package controllers
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"material_todo_go/database"
+	"material_todo_go/middleware"
+	"material_todo_go/models"
+	"material_todo_go/notifications"
+	"net/http"
+	"time"
+)
+
+// canAccessTaskGroup reports whether a mentioned user can see the task group
+func canAccessTaskGroup(taskGroup models.TaskGroup) func(uint) bool {
+	return func(userID uint) bool {
+		return taskGroupRole(taskGroup, userID) != ""
+	}
+}
+
+// canAccessWorkspace reports whether a mentioned user belongs to the workspace
+func canAccessWorkspace(workspaceID uint) func(uint) bool {
+	return func(userID uint) bool {
+		var count int64
+		database.DB.Model(&models.WorkspaceMember{}).Where("workspace_id = ? AND user_id = ?", workspaceID, userID).Count(&count)
+		return count > 0
+	}
+}
+
+// notifyAssignees tells newly assigned users about a task
+func notifyAssignees(actor models.User, task models.Task, userIDs []uint) {
+	for _, userID := range userIDs {
+		notifications.Send(models.Notification{
+			UserID:     userID,
+			Type:       models.NotificationAssignment,
+			ActorID:    actor.ID,
+			Message:    fmt.Sprintf("%s assigned you to task %q", actor.FullName, task.Title),
+			EntityType: notifications.EntityTask,
+			EntityID:   task.ID,
+		})
+	}
+}
+
+// notifyTaskGroupShared tells a user about a change to their access to a task group
+func notifyTaskGroupShared(actor models.User, taskGroup models.TaskGroup, userID uint, message string) {
+	notifications.Send(models.Notification{
+		UserID:     userID,
+		Type:       models.NotificationTaskGroupShared,
+		ActorID:    actor.ID,
+		Message:    message,
+		EntityType: notifications.EntityTaskGroup,
+		EntityID:   taskGroup.ID,
+	})
+}
+
+// GetNotifications lists the notifications of the authenticated user, newest first; unread=true keeps only unread ones
+func GetNotifications(c *gin.Context) {
+	userID := middleware.CurrentUser(c).ID
+	page, pageSize := pagination(c)
+
+	query := database.DB.Model(&models.Notification{}).Where("user_id = ?", userID)
+	if c.Query("unread") == "true" {
+		query = query.Where("read_at IS NULL")
+	}
+
+	var total, unread int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve notifications"})
+		return
+	}
+	database.DB.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&unread)
+
+	notificationList := []models.Notification{}
+	if err := query.Order("created_at DESC, id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&notificationList).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve notifications"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"notifications": notificationList,
+		"unread_count":  unread,
+		"total":         total,
+		"page":          page,
+		"page_size":     pageSize,
+	})
+}
+
+// GetUnreadNotificationCount returns the number of unread notifications, for the bell badge
+func GetUnreadNotificationCount(c *gin.Context) {
+	var unread int64
+	if err := database.DB.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", middleware.CurrentUser(c).ID).Count(&unread).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count notifications"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"unread_count": unread})
+}
+
+// setNotificationRead marks one notification of the authenticated user read or unread
+func setNotificationRead(c *gin.Context, read bool) {
+	var readAt interface{}
+	if read {
+		readAt = time.Now()
+	}
+
+	result := database.DB.Model(&models.Notification{}).
+		Where("id = ? AND user_id = ?", c.Param("id"), middleware.CurrentUser(c).ID).
+		Update("read_at", readAt)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notification"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// MarkNotificationRead marks a notification read
+func MarkNotificationRead(c *gin.Context) {
+	setNotificationRead(c, true)
+}
+
+// MarkNotificationUnread marks a notification unread again
+func MarkNotificationUnread(c *gin.Context) {
+	setNotificationRead(c, false)
+}
+
+// MarkAllNotificationsRead marks every unread notification of the authenticated user read
+func MarkAllNotificationsRead(c *gin.Context) {
+	if err := database.DB.Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", middleware.CurrentUser(c).ID).
+		Update("read_at", time.Now()).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notifications"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// GetNotificationPreferences returns how the authenticated user receives each notification type
+func GetNotificationPreferences(c *gin.Context) {
+	userID := middleware.CurrentUser(c).ID
+
+	preferences := []models.NotificationPreference{}
+	for _, notificationType := range notifications.Types {
+		preferences = append(preferences, notifications.Preference(userID, notificationType))
+	}
+
+	c.JSON(http.StatusOK, preferences)
+}
+
+// UpdateNotificationPreferences stores the preferences given for one or more notification types
+func UpdateNotificationPreferences(c *gin.Context) {
+	userID := middleware.CurrentUser(c).ID
+
+	var request []models.NotificationPreference
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	known := map[string]bool{}
+	for _, notificationType := range notifications.Types {
+		known[notificationType] = true
+	}
+	for _, preference := range request {
+		if !known[preference.Type] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown notification type " + preference.Type})
+			return
+		}
+	}
+
+	for _, preference := range request {
+		stored := notifications.Preference(userID, preference.Type)
+		stored.InApp, stored.Email = preference.InApp, preference.Email
+		if err := database.DB.Save(&stored).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update preferences"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
@@ -0,0 +1,283 @@
+package controllers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"log"
+	"material_todo_go/config"
+	"material_todo_go/database"
+	"material_todo_go/models"
+	"material_todo_go/oauth"
+	"material_todo_go/utils"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	oauthStateTTL     = 10 * time.Minute
+	oauthLoginCodeTTL = 2 * time.Minute
+)
+
+var errOAuthEmailNotVerified = errors.New("the provider did not confirm this email address")
+
+// redirectWithParams sends the browser back to the client redirect URI with extra query parameters
+func redirectWithParams(c *gin.Context, redirectURI string, params url.Values) {
+	target, err := url.Parse(redirectURI)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid redirect URI"})
+		return
+	}
+
+	query := target.Query()
+	for key, values := range params {
+		for _, value := range values {
+			query.Add(key, value)
+		}
+	}
+	target.RawQuery = query.Encode()
+	c.Redirect(http.StatusFound, target.String())
+}
+
+func isAllowedClientRedirect(redirectURI string) bool {
+	for _, allowed := range config.OAuthClientRedirects {
+		if redirectURI == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// OAuthAuthorize starts a social login: it records the client's PKCE challenge and redirects to the provider
+func OAuthAuthorize(c *gin.Context) {
+	provider, ok := config.OAuthProviders[c.Param("provider")]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown provider"})
+		return
+	}
+
+	redirectURI := c.Query("redirect_uri")
+	if !isAllowedClientRedirect(redirectURI) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Redirect URI is not allowed"})
+		return
+	}
+
+	challenge := c.Query("code_challenge")
+	if challenge == "" || c.DefaultQuery("code_challenge_method", "S256") != "S256" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "An S256 code_challenge is required"})
+		return
+	}
+
+	state, err := utils.GenerateToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start login"})
+		return
+	}
+	verifier, err := utils.GenerateToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start login"})
+		return
+	}
+
+	login := models.OAuthLogin{
+		Provider:          provider.Name,
+		StateHash:         utils.HashToken(state),
+		ProviderVerifier:  verifier,
+		ClientRedirectURI: redirectURI,
+		ClientState:       c.Query("state"),
+		ClientChallenge:   challenge,
+		ExpiresAt:         time.Now().Add(oauthStateTTL),
+	}
+	if err := database.DB.Create(&login).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start login"})
+		return
+	}
+
+	c.Redirect(http.StatusFound, oauth.AuthCodeURL(provider, state, verifier))
+}
+
+// OAuthCallback finishes the provider side of the login and hands the client a one-time login code
+func OAuthCallback(c *gin.Context) {
+	provider, ok := config.OAuthProviders[c.Param("provider")]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown provider"})
+		return
+	}
+
+	var login models.OAuthLogin
+	if err := database.DB.Where("state_hash = ? AND provider = ? AND expires_at > ? AND login_code_hash = ''",
+		utils.HashToken(c.Query("state")), provider.Name, time.Now()).First(&login).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired login state"})
+		return
+	}
+
+	fail := func(reason string) {
+		database.DB.Delete(&login)
+		redirectWithParams(c, login.ClientRedirectURI, url.Values{"error": {reason}, "state": {login.ClientState}})
+	}
+
+	if c.Query("error") != "" || c.Query("code") == "" {
+		fail("access_denied")
+		return
+	}
+
+	accessToken, err := oauth.Exchange(c.Request.Context(), provider, c.Query("code"), login.ProviderVerifier)
+	if err != nil {
+		log.Printf("OAuth code exchange with %s failed: %v", provider.Name, err)
+		fail("server_error")
+		return
+	}
+
+	profile, err := oauth.FetchProfile(c.Request.Context(), provider, accessToken)
+	if err != nil {
+		log.Printf("Fetching %s profile failed: %v", provider.Name, err)
+		fail("server_error")
+		return
+	}
+
+	user, err := linkOAuthUser(provider.Name, profile)
+	if err != nil {
+		log.Printf("Linking %s account failed: %v", provider.Name, err)
+		fail("account_not_linked")
+		return
+	}
+
+	loginCode, err := utils.GenerateToken()
+	if err != nil {
+		fail("server_error")
+		return
+	}
+
+	if err := database.DB.Model(&login).Updates(map[string]interface{}{
+		"login_code_hash": utils.HashToken(loginCode),
+		"user_id":         user.ID,
+		"expires_at":      time.Now().Add(oauthLoginCodeTTL),
+	}).Error; err != nil {
+		fail("server_error")
+		return
+	}
+
+	redirectWithParams(c, login.ClientRedirectURI, url.Values{"code": {loginCode}, "state": {login.ClientState}})
+}
+
+// linkOAuthUser finds the user for a provider identity, linking or creating an account by verified email
+func linkOAuthUser(provider string, profile oauth.Profile) (models.User, error) {
+	var user models.User
+
+	var identity models.OAuthIdentity
+	if err := database.DB.Where("provider = ? AND subject = ?", provider, profile.Subject).First(&identity).Error; err == nil {
+		err := database.DB.First(&user, identity.UserID).Error
+		return user, err
+	}
+
+	// Matching by email is only safe when the provider vouches for the address
+	if profile.Email == "" || !profile.EmailVerified {
+		return user, errOAuthEmailNotVerified
+	}
+	email := strings.ToLower(profile.Email)
+
+	if err := database.DB.Where("LOWER(email) = ?", email).First(&user).Error; err != nil {
+		// No account yet: create one that can only sign in through the provider until a password is set
+		hashedPassword, err := randomPasswordHash()
+		if err != nil {
+			return user, err
+		}
+
+		fullName := profile.Name
+		if fullName == "" {
+			fullName = email
+		}
+
+		user = models.User{
+			FullName:      fullName,
+			Email:         email,
+			Password:      hashedPassword,
+			Image:         "uploads/default_avatar.png",
+			EmailVerified: true,
+		}
+		if err := database.DB.Create(&user).Error; err != nil {
+			return user, err
+		}
+		if err := database.CreatePersonalWorkspace(&user); err != nil {
+			return user, err
+		}
+	} else if !user.EmailVerified {
+		// Whoever signed up with this address never proved they own it, so nothing they set up may survive
+		hashedPassword, err := randomPasswordHash()
+		if err != nil {
+			return user, err
+		}
+		user.Password = hashedPassword
+		user.EmailVerified = true
+		user.TOTPSecret = ""
+		user.TOTPEnabled = false
+		user.TOTPLastCounter = 0
+		if err := database.DB.Model(&user).Select("password", "email_verified", "totp_secret", "totp_enabled", "totp_last_counter").
+			Updates(&user).Error; err != nil {
+			return user, err
+		}
+		if err := database.DB.Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return user, err
+		}
+		if err := invalidateUserTokens(user.ID); err != nil {
+			return user, err
+		}
+	}
+
+	identity = models.OAuthIdentity{
+		UserID:   user.ID,
+		Provider: provider,
+		Subject:  profile.Subject,
+		Email:    email,
+	}
+	return user, database.DB.Create(&identity).Error
+}
+
+// randomPasswordHash hashes a random password nobody knows, for accounts that must go through a reset to use one
+func randomPasswordHash() (string, error) {
+	randomPassword, err := utils.GenerateToken()
+	if err != nil {
+		return "", err
+	}
+	return utils.HashPassword(randomPassword)
+}
+
+// OAuthToken exchanges the one-time login code and the client's PKCE verifier for tokens
+func OAuthToken(c *gin.Context) {
+	var request struct {
+		Code         string `json:"code"`
+		CodeVerifier string `json:"code_verifier"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil || request.Code == "" || request.CodeVerifier == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Code and code_verifier are required"})
+		return
+	}
+
+	var login models.OAuthLogin
+	if err := database.DB.Where("login_code_hash = ? AND expires_at > ? AND used_at IS NULL", utils.HashToken(request.Code), time.Now()).
+		First(&login).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired code"})
+		return
+	}
+
+	if oauth.ChallengeS256(request.CodeVerifier) != login.ClientChallenge {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid code verifier"})
+		return
+	}
+
+	// Single use: only the request that flips used_at may continue
+	result := database.DB.Model(&models.OAuthLogin{}).Where("id = ? AND used_at IS NULL", login.ID).Update("used_at", time.Now())
+	if result.Error != nil || result.RowsAffected == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired code"})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, login.UserID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	completeLogin(c, user)
+}
@@ -0,0 +1,106 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+	"material_todo_go/database"
+	"material_todo_go/middleware"
+	"material_todo_go/models"
+	"material_todo_go/utils"
+	"net/http"
+	"strings"
+	"time"
+)
+
+func isKnownScope(scope string) bool {
+	for _, known := range middleware.Scopes {
+		if scope == known {
+			return true
+		}
+	}
+	return false
+}
+
+// GetPersonalTokens lists the personal access tokens of the authenticated user
+func GetPersonalTokens(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	var tokens []models.PersonalAccessToken
+	if err := database.DB.Where("user_id = ?", user.ID).Order("created_at DESC").Find(&tokens).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve tokens"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// CreatePersonalToken creates a named, scoped token. The plain token is only returned once.
+func CreatePersonalToken(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	var request struct {
+		Name          string   `json:"name"`
+		Scopes        []string `json:"scopes"`
+		ExpiresInDays int      `json:"expires_in_days"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil || request.Name == "" || len(request.Scopes) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name and scopes are required"})
+		return
+	}
+
+	for _, scope := range request.Scopes {
+		if !isKnownScope(scope) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown scope " + scope, "allowed_scopes": middleware.Scopes})
+			return
+		}
+	}
+
+	if request.ExpiresInDays < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expires_in_days must be positive"})
+		return
+	}
+
+	random, err := utils.GenerateToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+	token := middleware.PersonalTokenPrefix + random
+
+	pat := models.PersonalAccessToken{
+		UserID:    user.ID,
+		Name:      request.Name,
+		TokenHash: utils.HashToken(token),
+		Prefix:    token[:len(middleware.PersonalTokenPrefix)+6],
+		Scopes:    strings.Join(request.Scopes, " "),
+	}
+	if request.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, request.ExpiresInDays)
+		pat.ExpiresAt = &expiresAt
+	}
+
+	if err := database.DB.Create(&pat).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create token"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"token": token, "personal_access_token": pat})
+}
+
+// RevokePersonalToken revokes one of the authenticated user's tokens
+func RevokePersonalToken(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	result := database.DB.Model(&models.PersonalAccessToken{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", c.Param("id"), user.ID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke token"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Token not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Token revoked successfully"})
+}
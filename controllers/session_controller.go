@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+	"material_todo_go/audit"
+	"material_todo_go/database"
+	"material_todo_go/middleware"
+	"material_todo_go/models"
+	"net/http"
+	"time"
+)
+
+// startSession records a login from the requesting device. Clients name the device with the X-Device-Name header.
+func startSession(c *gin.Context, user models.User) (models.Session, error) {
+	session := models.Session{
+		UserID:     user.ID,
+		DeviceName: c.GetHeader("X-Device-Name"),
+		UserAgent:  c.Request.UserAgent(),
+		IP:         c.ClientIP(),
+		LastSeenAt: time.Now(),
+	}
+	err := database.DB.Create(&session).Error
+	return session, err
+}
+
+// loginWithNewSession starts a session for the requesting device and responds with its tokens
+func loginWithNewSession(c *gin.Context, user models.User) {
+	session, err := startSession(c, user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create session"})
+		return
+	}
+
+	tokens, err := issueTokens(user, session.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	audit.Record(c, audit.Entry{Event: audit.EventLoginSucceeded, ActorID: user.ID, SubjectID: user.ID, Email: user.Email,
+		Metadata: map[string]interface{}{"session_id": session.ID, "method": c.FullPath()}})
+	c.JSON(http.StatusOK, tokens)
+}
+
+// revokeSession ends a session and revokes its refresh tokens; its access tokens stop working immediately
+func revokeSession(sessionID uint) error {
+	if err := database.DB.Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", sessionID).
+		Update("revoked_at", time.Now()).Error; err != nil {
+		return err
+	}
+
+	return database.DB.Model(&models.RefreshToken{}).
+		Where("session_id = ? AND revoked_at IS NULL", sessionID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// revokeOtherSessions ends every session of the user except the one making the request
+func revokeOtherSessions(userID, keepSessionID uint) error {
+	var sessions []models.Session
+	if err := database.DB.Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userID, keepSessionID).Find(&sessions).Error; err != nil {
+		return err
+	}
+
+	for _, session := range sessions {
+		if err := revokeSession(session.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetSessions lists the active sessions of the authenticated user
+func GetSessions(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	currentSessionID := middleware.CurrentClaims(c).SessionID
+
+	var sessions []models.Session
+	if err := database.DB.Where("user_id = ? AND revoked_at IS NULL", user.ID).Order("last_seen_at DESC").Find(&sessions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve sessions"})
+		return
+	}
+
+	response := []gin.H{}
+	for _, session := range sessions {
+		response = append(response, gin.H{
+			"id":           session.ID,
+			"device_name":  session.DeviceName,
+			"user_agent":   session.UserAgent,
+			"ip":           session.IP,
+			"created_at":   session.CreatedAt,
+			"last_seen_at": session.LastSeenAt,
+			"current":      session.ID == currentSessionID,
+		})
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// RevokeSession logs out one of the authenticated user's sessions
+func RevokeSession(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	var session models.Session
+	if err := database.DB.Where("id = ? AND user_id = ? AND revoked_at IS NULL", c.Param("id"), user.ID).First(&session).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	}
+
+	if err := revokeSession(session.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked successfully"})
+}
@@ -0,0 +1,184 @@
+package controllers
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"material_todo_go/database"
+	"material_todo_go/middleware"
+	"material_todo_go/models"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// taskResponse renders a task with its task group name and assignees.
+// TaskGroup and Assignees.User must be preloaded.
+func taskResponse(task models.Task) map[string]interface{} {
+	assignees := []gin.H{}
+	for _, assignee := range task.Assignees {
+		assignees = append(assignees, gin.H{
+			"user_id":   assignee.UserID,
+			"full_name": assignee.User.FullName,
+			"email":     assignee.User.Email,
+			"image":     assignee.User.Image,
+		})
+	}
+
+	return map[string]interface{}{
+		"id":              task.ID,
+		"title":           task.Title,
+		"description":     task.Description,
+		"task_group_id":   task.TaskGroupID,
+		"task_group_name": task.TaskGroup.Name,
+		"start_date":      task.StartDate,
+		"finish_date":     task.FinishDate,
+		"status":          task.Status,
+		"assignees":       assignees,
+	}
+}
+
+// validateAssignees removes duplicates and checks every user can access the task group, responding 400 otherwise
+func validateAssignees(c *gin.Context, taskGroup models.TaskGroup, userIDs []uint) ([]uint, bool) {
+	seen := map[uint]bool{}
+	valid := []uint{}
+	for _, userID := range userIDs {
+		if seen[userID] {
+			continue
+		}
+		seen[userID] = true
+
+		if taskGroupRole(taskGroup, userID) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("User %d has no access to this task group", userID)})
+			return nil, false
+		}
+		valid = append(valid, userID)
+	}
+	return valid, true
+}
+
+// joinIDs formats IDs in ascending order for task history
+func joinIDs(ids []uint) string {
+	sorted := append([]uint{}, ids...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	parts := make([]string, len(sorted))
+	for i, id := range sorted {
+		parts[i] = strconv.FormatUint(uint64(id), 10)
+	}
+	return strings.Join(parts, ",")
+}
+
+// addedIDs returns the IDs in after that are not in before
+func addedIDs(before, after []uint) []uint {
+	existing := map[uint]bool{}
+	for _, id := range before {
+		existing[id] = true
+	}
+
+	added := []uint{}
+	for _, id := range after {
+		if !existing[id] {
+			added = append(added, id)
+		}
+	}
+	return added
+}
+
+// currentAssigneeIDs returns the IDs of the users assigned to a task
+func currentAssigneeIDs(tx *gorm.DB, taskID uint) []uint {
+	var ids []uint
+	tx.Model(&models.TaskAssignee{}).Where("task_id = ?", taskID).Pluck("user_id", &ids)
+	return ids
+}
+
+// setTaskAssignees replaces the assignees of a task and records the change in its history
+func setTaskAssignees(tx *gorm.DB, taskID uint, userIDs []uint, actorID uint) error {
+	oldValue, newValue := joinIDs(currentAssigneeIDs(tx, taskID)), joinIDs(userIDs)
+	if oldValue == newValue {
+		return nil
+	}
+
+	if err := tx.Where("task_id = ?", taskID).Delete(&models.TaskAssignee{}).Error; err != nil {
+		return err
+	}
+	for _, userID := range userIDs {
+		if err := tx.Create(&models.TaskAssignee{TaskID: taskID, UserID: userID, AssignedByID: actorID}).Error; err != nil {
+			return err
+		}
+	}
+
+	return tx.Create(&models.TaskHistory{
+		TaskID:   taskID,
+		ActorID:  actorID,
+		Field:    "assignees",
+		OldValue: oldValue,
+		NewValue: newValue,
+	}).Error
+}
+
+// unassignWithoutAccess removes a user from the tasks of a task group once they can no longer access it
+func unassignWithoutAccess(taskGroup models.TaskGroup, userID, actorID uint) error {
+	if taskGroupRole(taskGroup, userID) != "" {
+		return nil
+	}
+
+	var taskIDs []uint
+	database.DB.Model(&models.TaskAssignee{}).
+		Where("user_id = ? AND task_id IN (?)", userID, database.DB.Model(&models.Task{}).Select("id").Where("task_group_id = ?", taskGroup.ID)).
+		Pluck("task_id", &taskIDs)
+
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		for _, taskID := range taskIDs {
+			kept := []uint{}
+			for _, id := range currentAssigneeIDs(tx, taskID) {
+				if id != userID {
+					kept = append(kept, id)
+				}
+			}
+			if err := setTaskAssignees(tx, taskID, kept, actorID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetTasksAssignedToMe returns the tasks assigned to the authenticated user across every group they can access
+func GetTasksAssignedToMe(c *gin.Context) {
+	userID := middleware.CurrentUser(c).ID
+
+	var tasks []models.Task
+	if err := database.DB.Preload("TaskGroup").Preload("Assignees.User").
+		Where("id IN (?) AND task_group_id IN (?)",
+			database.DB.Model(&models.TaskAssignee{}).Select("task_id").Where("user_id = ?", userID),
+			accessibleTaskGroupIDs(userID)).
+		Order("finish_date").Find(&tasks).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve tasks"})
+		return
+	}
+
+	response := []map[string]interface{}{}
+	for _, task := range tasks {
+		response = append(response, taskResponse(task))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetTaskHistory lists the recorded changes of a task, oldest first
+func GetTaskHistory(c *gin.Context) {
+	task, ok := findTask(c, c.Param("id"), models.TaskGroupViewer)
+	if !ok {
+		return
+	}
+
+	history := []models.TaskHistory{}
+	if err := database.DB.Where("task_id = ?", task.ID).Order("created_at, id").Find(&history).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
@@ -0,0 +1,221 @@
+package controllers
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"material_todo_go/database"
+	"material_todo_go/middleware"
+	"material_todo_go/models"
+	"material_todo_go/notifications"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const maxCommentLength = 10000
+
+// commentResponse renders a comment with its author; Author must be preloaded
+func commentResponse(comment models.TaskComment) gin.H {
+	return gin.H{
+		"id":        comment.ID,
+		"task_id":   comment.TaskID,
+		"parent_id": comment.ParentID,
+		"author": gin.H{
+			"user_id":   comment.AuthorID,
+			"full_name": comment.Author.FullName,
+			"image":     comment.Author.Image,
+		},
+		"body":       comment.Body,
+		"deleted":    comment.Deleted,
+		"edited_at":  comment.EditedAt,
+		"created_at": comment.CreatedAt,
+	}
+}
+
+// commentBody validates the markdown body of a comment, responding 400 when it is empty or too long
+func commentBody(c *gin.Context, body string) (string, bool) {
+	body = strings.TrimSpace(body)
+	if body == "" || len(body) > maxCommentLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Comment must contain between 1 and 10000 characters"})
+		return "", false
+	}
+	return body, true
+}
+
+// findAuthoredComment loads a comment of the task written by the authenticated user
+func findAuthoredComment(c *gin.Context, task models.Task) (models.TaskComment, bool) {
+	var comment models.TaskComment
+	if err := database.DB.Where("id = ? AND task_id = ? AND deleted = ?", c.Param("comment_id"), task.ID, false).First(&comment).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return comment, false
+	}
+
+	if comment.AuthorID != middleware.CurrentUser(c).ID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the author can change this comment"})
+		return comment, false
+	}
+	return comment, true
+}
+
+// GetTaskComments lists the threads of a task, oldest first, one page of top-level comments at a time
+func GetTaskComments(c *gin.Context) {
+	task, ok := findTask(c, c.Param("id"), models.TaskGroupViewer)
+	if !ok {
+		return
+	}
+
+	page, pageSize := pagination(c)
+	query := database.DB.Model(&models.TaskComment{}).Where("task_id = ? AND parent_id IS NULL", task.ID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve comments"})
+		return
+	}
+
+	var threads []models.TaskComment
+	if err := query.Preload("Author").Order("created_at, id").Offset((page - 1) * pageSize).Limit(pageSize).Find(&threads).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve comments"})
+		return
+	}
+
+	rootIDs := make([]uint, len(threads))
+	for i, thread := range threads {
+		rootIDs[i] = thread.ID
+	}
+
+	var replies []models.TaskComment
+	if len(rootIDs) > 0 {
+		database.DB.Preload("Author").Where("parent_id IN ?", rootIDs).Order("created_at, id").Find(&replies)
+	}
+
+	repliesByRoot := map[uint][]gin.H{}
+	for _, reply := range replies {
+		repliesByRoot[*reply.ParentID] = append(repliesByRoot[*reply.ParentID], commentResponse(reply))
+	}
+
+	response := []gin.H{}
+	for _, thread := range threads {
+		item := commentResponse(thread)
+		item["replies"] = append([]gin.H{}, repliesByRoot[thread.ID]...)
+		response = append(response, item)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"comments": response, "total": total, "page": page, "page_size": pageSize})
+}
+
+// CreateTaskComment adds a comment to a task, or a reply when parent_id is given
+func CreateTaskComment(c *gin.Context) {
+	task, ok := findTask(c, c.Param("id"), models.TaskGroupEditor)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		Body     string `json:"body"`
+		ParentID *uint  `json:"parent_id"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	body, ok := commentBody(c, request.Body)
+	if !ok {
+		return
+	}
+
+	comment := models.TaskComment{TaskID: task.ID, AuthorID: middleware.CurrentUser(c).ID, Body: body}
+	if request.ParentID != nil {
+		var parent models.TaskComment
+		if err := database.DB.Where("id = ? AND task_id = ?", *request.ParentID, task.ID).First(&parent).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parent comment not found"})
+			return
+		}
+
+		// Threads are one level deep, a reply to a reply joins the same thread
+		rootID := parent.ID
+		if parent.ParentID != nil {
+			rootID = *parent.ParentID
+		}
+		comment.ParentID = &rootID
+	}
+
+	if err := database.DB.Create(&comment).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create comment"})
+		return
+	}
+
+	comment.Author = middleware.CurrentUser(c)
+	notifications.SendMentions(notifications.Mentions(comment.Body), canAccessTaskGroup(task.TaskGroup), comment.Author,
+		fmt.Sprintf("a comment on task %q", task.Title), notifications.EntityTask, task.ID)
+
+	c.JSON(http.StatusCreated, commentResponse(comment))
+}
+
+// UpdateTaskComment edits the body of a comment written by the authenticated user
+func UpdateTaskComment(c *gin.Context) {
+	// Same role as posting, so authors downgraded to viewer can no longer change their comments
+	task, ok := findTask(c, c.Param("id"), models.TaskGroupEditor)
+	if !ok {
+		return
+	}
+
+	comment, ok := findAuthoredComment(c, task)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		Body string `json:"body"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	body, ok := commentBody(c, request.Body)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	if err := database.DB.Model(&comment).Updates(map[string]interface{}{"body": body, "edited_at": now}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update comment"})
+		return
+	}
+
+	notifications.SendMentions(notifications.NewMentions(comment.Body, body), canAccessTaskGroup(task.TaskGroup), middleware.CurrentUser(c),
+		fmt.Sprintf("a comment on task %q", task.Title), notifications.EntityTask, task.ID)
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// DeleteTaskComment removes a comment written by the authenticated user
+func DeleteTaskComment(c *gin.Context) {
+	task, ok := findTask(c, c.Param("id"), models.TaskGroupEditor)
+	if !ok {
+		return
+	}
+
+	comment, ok := findAuthoredComment(c, task)
+	if !ok {
+		return
+	}
+
+	var replies int64
+	database.DB.Model(&models.TaskComment{}).Where("parent_id = ?", comment.ID).Count(&replies)
+
+	var err error
+	if replies > 0 {
+		err = database.DB.Model(&comment).Updates(map[string]interface{}{"body": "", "deleted": true}).Error
+	} else {
+		err = database.DB.Delete(&comment).Error
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete comment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Comment deleted successfully"})
+}
@@ -1,72 +1,79 @@
 package controllers
 
 import (
+	"fmt"
 	"material_todo_go/database"
+	"material_todo_go/middleware"
 	"material_todo_go/models"
-	"material_todo_go/utils"
+	"material_todo_go/notifications"
 	"net/http"
-	"strings"
 	"time"
 	_ "time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-// Extract and validate JWT token
-func getUserFromToken(c *gin.Context) (string, bool) {
-	authHeader := c.GetHeader("Authorization")
-	if authHeader == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization token required"})
-		return "", false
+// CreateTask creates a new task in a task group the authenticated user can edit
+func CreateTask(c *gin.Context) {
+	var request struct {
+		models.Task
+		AssigneeIDs []uint `json:"assignee_ids"`
 	}
-
-	// Ensure "Bearer <token>" format
-	parts := strings.Split(authHeader, " ")
-	if len(parts) != 2 || parts[0] != "Bearer" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid Authorization header format"})
-		return "", false
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
 	}
+	task := request.Task
 
-	tokenString := parts[1]
-	email, err := utils.ParseJWT(tokenString)
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
-		return "", false
+	taskGroup, ok := findTaskGroup(c, task.TaskGroupID, models.TaskGroupEditor)
+	if !ok {
+		return
 	}
 
-	return email, true
-}
-
-// CreateTask creates a new task
-func CreateTask(c *gin.Context) {
-	_, valid := getUserFromToken(c)
-	if !valid {
+	assigneeIDs, ok := validateAssignees(c, taskGroup, request.AssigneeIDs)
+	if !ok {
 		return
 	}
 
-	var task models.Task
-	if err := c.ShouldBindJSON(&task); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+	// New tasks start in the first status of the workflow unless told otherwise
+	if task.Status == "" {
+		if statuses := taskGroupStatuses(taskGroup.ID); len(statuses) > 0 {
+			task.Status = statuses[0].Name
+		}
+	}
+	if !checkTaskStatus(c, taskGroup.ID, 0, "", task.Status) {
 		return
 	}
 
-	if err := database.DB.Create(&task).Error; err != nil {
+	task.ID = 0
+	task.UserID = taskGroup.UserID
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(&task).Error; err != nil {
+			return err
+		}
+		return setTaskAssignees(tx, task.ID, assigneeIDs, middleware.CurrentUser(c).ID)
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create task"})
 		return
 	}
 
+	recordActivity(c, task.TaskGroupID, models.ActivityTaskCreated, &task.ID, map[string]interface{}{"title": task.Title})
+
+	actor := middleware.CurrentUser(c)
+	notifyAssignees(actor, task, assigneeIDs)
+	notifications.SendMentions(notifications.Mentions(task.Description), canAccessTaskGroup(taskGroup), actor,
+		fmt.Sprintf("task %q", task.Title), notifications.EntityTask, task.ID)
+
 	c.JSON(http.StatusCreated, gin.H{})
 }
 
-// GetAllTasks returns all tasks
+// GetAllTasks returns all tasks in the task groups of the active workspace
 func GetAllTasks(c *gin.Context) {
-	_, valid := getUserFromToken(c)
-	if !valid {
-		return
-	}
-
 	var tasks []models.Task
-	if err := database.DB.Preload("TaskGroup").Find(&tasks).Error; err != nil {
+	if err := database.DB.Preload("TaskGroup").Preload("Assignees.User").Where("task_group_id IN (?)", workspaceTaskGroupIDs(c)).Find(&tasks).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve tasks"})
 		return
 	}
@@ -74,16 +81,7 @@ func GetAllTasks(c *gin.Context) {
 	// Build the response with task group names
 	var response []map[string]interface{}
 	for _, task := range tasks {
-		response = append(response, map[string]interface{}{
-			"id":              task.ID,
-			"title":           task.Title,
-			"description":     task.Description,
-			"task_group_id":   task.TaskGroupID,
-			"task_group_name": task.TaskGroup.Name,
-			"start_date":      task.StartDate,
-			"finish_date":     task.FinishDate,
-			"status":          task.Status,
-		})
+		response = append(response, taskResponse(task))
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -91,53 +89,34 @@ func GetAllTasks(c *gin.Context) {
 
 // GetTask retrieves a task by ID
 func GetTask(c *gin.Context) {
-	_, valid := getUserFromToken(c)
-	if !valid {
-		return
-	}
-
-	taskID := c.Param("id")
-	var task models.Task
-
-	if err := database.DB.Preload("TaskGroup").First(&task, taskID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+	task, ok := findTask(c, c.Param("id"), models.TaskGroupViewer)
+	if !ok {
 		return
 	}
 
 	// Response with task group name
-	c.JSON(http.StatusOK, map[string]interface{}{
-		"id":              task.ID,
-		"title":           task.Title,
-		"description":     task.Description,
-		"task_group_id":   task.TaskGroupID,
-		"task_group_name": task.TaskGroup.Name,
-		"start_date":      task.StartDate,
-		"finish_date":     task.FinishDate,
-		"status":          task.Status,
-	})
+	c.JSON(http.StatusOK, taskResponse(task))
 }
 
 // UpdateTask updates an existing task
 func UpdateTask(c *gin.Context) {
-	_, valid := getUserFromToken(c)
-	if !valid {
+	task, ok := findTask(c, c.Param("id"), models.TaskGroupEditor)
+	if !ok {
 		return
 	}
 
-	taskID := c.Param("id")
-	var task models.Task
-
-	if err := database.DB.First(&task, taskID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
-		return
+	var updatedData struct {
+		models.Task
+		AssigneeIDs *[]uint `json:"assignee_ids"` // omitted keeps the assignees, [] removes them
 	}
-
-	var updatedData models.Task
 	if err := c.ShouldBindJSON(&updatedData); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
 		return
 	}
 
+	before := task
+	previousDescription := task.Description
+
 	// Update fields
 	if updatedData.Title != "" {
 		task.Title = updatedData.Title
@@ -145,51 +124,116 @@ func UpdateTask(c *gin.Context) {
 	if updatedData.Description != "" {
 		task.Description = updatedData.Description
 	}
+	taskGroup := task.TaskGroup
+	previousAssignees := currentAssigneeIDs(database.DB, task.ID)
+	assigneeIDs := previousAssignees
+	if updatedData.TaskGroupID != 0 && updatedData.TaskGroupID != task.TaskGroupID {
+		// Tasks can only move to groups the caller can edit, and belong to that group's owner
+		var ok bool
+		taskGroup, ok = findTaskGroup(c, updatedData.TaskGroupID, models.TaskGroupEditor)
+		if !ok {
+			return
+		}
+		task.TaskGroupID = taskGroup.ID
+		task.UserID = taskGroup.UserID
+
+		// Tasks keep their status if the new group's workflow has it and start over otherwise
+		if updatedData.Status == "" && statusCategory(taskGroup.ID, task.Status) == "" {
+			if statuses := taskGroupStatuses(taskGroup.ID); len(statuses) > 0 {
+				task.Status = statuses[0].Name
+			}
+		}
+
+		// Assignees without access to the new group are dropped
+		kept := []uint{}
+		for _, userID := range assigneeIDs {
+			if taskGroupRole(taskGroup, userID) != "" {
+				kept = append(kept, userID)
+			}
+		}
+		assigneeIDs = kept
+	}
 	if updatedData.Status != "" {
 		task.Status = updatedData.Status
 	}
-	if updatedData.TaskGroupID != 0 {
-		task.TaskGroupID = updatedData.TaskGroupID
+	if task.Status != before.Status || task.TaskGroupID != before.TaskGroupID {
+		// Transitions only apply within a group's workflow
+		from := before.Status
+		if task.TaskGroupID != before.TaskGroupID {
+			from = ""
+		}
+		if !checkTaskStatus(c, task.TaskGroupID, task.ID, from, task.Status) {
+			return
+		}
+	}
+	if updatedData.AssigneeIDs != nil {
+		var ok bool
+		if assigneeIDs, ok = validateAssignees(c, taskGroup, *updatedData.AssigneeIDs); !ok {
+			return
+		}
 	}
 	if !updatedData.StartDate.IsZero() {
 		task.StartDate = updatedData.StartDate
 	}
-	if !updatedData.FinishDate.IsZero() {
+	if !updatedData.FinishDate.IsZero() && !updatedData.FinishDate.Equal(task.FinishDate) {
 		task.FinishDate = updatedData.FinishDate
+		task.DueSoonNotifiedAt = nil
 	}
 
-	if err := database.DB.Save(&task).Error; err != nil {
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Save(&task).Error; err != nil {
+			return err
+		}
+		return setTaskAssignees(tx, task.ID, assigneeIDs, middleware.CurrentUser(c).ID)
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update task"})
 		return
 	}
 
+	if task.TaskGroupID != before.TaskGroupID {
+		move := map[string]interface{}{"title": task.Title, "from_task_group_id": before.TaskGroupID, "to_task_group_id": task.TaskGroupID}
+		recordActivity(c, before.TaskGroupID, models.ActivityTaskMovedOut, &task.ID, move)
+		recordActivity(c, task.TaskGroupID, models.ActivityTaskMovedIn, &task.ID, move)
+	}
+	fields := changedTaskFields(before, task)
+	if joinIDs(previousAssignees) != joinIDs(assigneeIDs) {
+		fields = append(fields, "assignees")
+	}
+	if task.Status != before.Status && statusCategory(task.TaskGroupID, task.Status) == models.StatusCategoryDone {
+		recordActivity(c, task.TaskGroupID, models.ActivityTaskCompleted, &task.ID, map[string]interface{}{"title": task.Title})
+	}
+	if len(fields) > 0 {
+		recordActivity(c, task.TaskGroupID, models.ActivityTaskUpdated, &task.ID, map[string]interface{}{"title": task.Title, "fields": fields})
+	}
+
+	actor := middleware.CurrentUser(c)
+	notifyAssignees(actor, task, addedIDs(previousAssignees, assigneeIDs))
+	notifications.SendMentions(notifications.NewMentions(previousDescription, task.Description), canAccessTaskGroup(taskGroup), actor,
+		fmt.Sprintf("task %q", task.Title), notifications.EntityTask, task.ID)
+
 	c.JSON(http.StatusOK, gin.H{})
 }
 
 // DeleteTask deletes a task by ID
 func DeleteTask(c *gin.Context) {
-	_, valid := getUserFromToken(c)
-	if !valid {
+	task, ok := findTask(c, c.Param("id"), models.TaskGroupEditor)
+	if !ok {
 		return
 	}
 
-	taskID := c.Param("id")
-	if err := database.DB.Delete(&models.Task{}, taskID).Error; err != nil {
+	if err := database.DB.Delete(&task).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete task"})
 		return
 	}
+	recordActivity(c, task.TaskGroupID, models.ActivityTaskDeleted, &task.ID, map[string]interface{}{"title": task.Title})
 
 	c.JSON(http.StatusOK, gin.H{"message": "Task deleted successfully"})
 }
 
 func GetTasksByStatusTODO(c *gin.Context) {
-	_, valid := getUserFromToken(c)
-	if !valid {
-		return
-	}
-
 	var tasks []models.Task
-	if err := database.DB.Preload("TaskGroup").Where("status = ?", "TODO").Find(&tasks).Error; err != nil {
+	if err := database.DB.Preload("TaskGroup").Preload("Assignees.User").Where("task_group_id IN (?) AND "+database.TaskStatusInCategory, workspaceTaskGroupIDs(c), models.StatusCategoryTodo).Find(&tasks).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve TODO tasks"})
 		return
 	}
@@ -202,29 +246,15 @@ func GetTasksByStatusTODO(c *gin.Context) {
 	}
 
 	for _, task := range tasks {
-		response = append(response, map[string]interface{}{
-			"id":              task.ID,
-			"title":           task.Title,
-			"description":     task.Description,
-			"task_group_id":   task.TaskGroupID,
-			"task_group_name": task.TaskGroup.Name,
-			"start_date":      task.StartDate,
-			"finish_date":     task.FinishDate,
-			"status":          task.Status,
-		})
+		response = append(response, taskResponse(task))
 	}
 
 	c.JSON(http.StatusOK, response)
 }
 
 func GetTasksByStatusInProgress(c *gin.Context) {
-	_, valid := getUserFromToken(c)
-	if !valid {
-		return
-	}
-
 	var tasks []models.Task
-	if err := database.DB.Preload("TaskGroup").Where("status = ?", "IN PROGRESS").Find(&tasks).Error; err != nil {
+	if err := database.DB.Preload("TaskGroup").Preload("Assignees.User").Where("task_group_id IN (?) AND "+database.TaskStatusInCategory, workspaceTaskGroupIDs(c), models.StatusCategoryInProgress).Find(&tasks).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve IN PROGRESS tasks"})
 		return
 	}
@@ -237,16 +267,7 @@ func GetTasksByStatusInProgress(c *gin.Context) {
 	}
 
 	for _, task := range tasks {
-		response = append(response, map[string]interface{}{
-			"id":              task.ID,
-			"title":           task.Title,
-			"description":     task.Description,
-			"task_group_id":   task.TaskGroupID,
-			"task_group_name": task.TaskGroup.Name,
-			"start_date":      task.StartDate,
-			"finish_date":     task.FinishDate,
-			"status":          task.Status,
-		})
+		response = append(response, taskResponse(task))
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -265,27 +286,9 @@ func GetTasksByFinishDate(c *gin.Context) {
 		return
 	}
 
-	authHeader := c.GetHeader("Authorization")
-	if authHeader == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization token required"})
-		return
-	}
-
-	parts := strings.Split(authHeader, " ")
-	if len(parts) != 2 || parts[0] != "Bearer" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid Authorization header format"})
-		return
-	}
-
-	_, err = utils.ParseJWT(parts[1])
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
-		return
-	}
-
 	// Query tasks with their associated task group
 	var tasks []models.Task
-	if err := database.DB.Preload("TaskGroup").Where("DATE(finish_date) = ?", finishDate.Format("2006-01-02")).Find(&tasks).Error; err != nil {
+	if err := database.DB.Preload("TaskGroup").Preload("Assignees.User").Where("task_group_id IN (?) AND DATE(finish_date) = ?", workspaceTaskGroupIDs(c), finishDate.Format("2006-01-02")).Find(&tasks).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve tasks"})
 		return
 	}
@@ -293,16 +296,7 @@ func GetTasksByFinishDate(c *gin.Context) {
 	// Add task group names to the response
 	var response []map[string]interface{}
 	for _, task := range tasks {
-		response = append(response, map[string]interface{}{
-			"id":              task.ID,
-			"title":           task.Title,
-			"description":     task.Description,
-			"task_group_id":   task.TaskGroupID,
-			"task_group_name": task.TaskGroup.Name,
-			"start_date":      task.StartDate,
-			"finish_date":     task.FinishDate,
-			"status":          task.Status,
-		})
+		response = append(response, taskResponse(task))
 	}
 
 	c.JSON(http.StatusOK, gin.H{"tasks": response})
@@ -1,31 +1,19 @@
 package controllers
 
 import (
+	"fmt"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 	"material_todo_go/database"
+	"material_todo_go/middleware"
 	"material_todo_go/models"
-	"material_todo_go/utils"
 	"net/http"
-	"strings"
 )
 
-// CreateTaskGroup handles creating a new task group
+// CreateTaskGroup handles creating a new task group for the authenticated user in the active workspace
 func CreateTaskGroup(c *gin.Context) {
-	authHeader := c.GetHeader("Authorization")
-	if authHeader == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization token required"})
-		return
-	}
-
-	parts := strings.Split(authHeader, " ")
-	if len(parts) != 2 || parts[0] != "Bearer" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid Authorization header format"})
-		return
-	}
-
-	_, err := utils.ParseJWT(parts[1])
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+	if !middleware.WorkspaceRoleAtLeast(middleware.CurrentWorkspaceRole(c), models.WorkspaceRoleMember) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Viewers cannot create task groups"})
 		return
 	}
 
@@ -35,33 +23,34 @@ func CreateTaskGroup(c *gin.Context) {
 		return
 	}
 
-	database.DB.Create(&taskGroup)
+	// The owner is always the caller, whatever the body says
+	taskGroup.ID = 0
+	taskGroup.UserID = middleware.CurrentUser(c).ID
+	taskGroup.WorkspaceID = middleware.CurrentWorkspace(c).ID
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&taskGroup).Error; err != nil {
+			return err
+		}
+		return database.CreateDefaultStatuses(tx, taskGroup.ID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create task group"})
+		return
+	}
+	recordActivity(c, taskGroup.ID, models.ActivityGroupCreated, nil, map[string]interface{}{"name": taskGroup.Name})
 	c.JSON(http.StatusCreated, gin.H{})
 }
 
-// GetTaskGroups retrieves all task groups
+// GetTaskGroups retrieves the task groups of the active workspace the authenticated user can access
 func GetTaskGroups(c *gin.Context) {
-	authHeader := c.GetHeader("Authorization")
-	if authHeader == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization token required"})
-		return
-	}
-
-	parts := strings.Split(authHeader, " ")
-	if len(parts) != 2 || parts[0] != "Bearer" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid Authorization header format"})
-		return
-	}
+	userID := middleware.CurrentUser(c).ID
 
-	_, err := utils.ParseJWT(parts[1])
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+	var taskGroups []models.TaskGroup
+	if err := database.DB.Where("id IN (?)", workspaceTaskGroupIDs(c)).Find(&taskGroups).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve task groups"})
 		return
 	}
 
-	var taskGroups []models.TaskGroup
-	database.DB.Find(&taskGroups)
-
 	// Define response struct
 	type TaskGroupResponse struct {
 		ID              uint   `json:"id"`
@@ -71,11 +60,13 @@ func GetTaskGroups(c *gin.Context) {
 		BackgroundColor string `json:"background_color"`
 		IconColor       string `json:"icon_color"`
 		UserID          uint   `json:"user_id"`
+		WorkspaceID     uint   `json:"workspace_id"`
+		Role            string `json:"role"`
 		TotalTasks      int64  `json:"total_tasks"`
 		CompletionRate  int    `json:"completion_rate"`
 	}
 
-	var response []TaskGroupResponse
+	response := []TaskGroupResponse{}
 
 	for _, group := range taskGroups {
 		var totalTasks int64
@@ -84,8 +75,8 @@ func GetTaskGroups(c *gin.Context) {
 		// Count total tasks for this task group
 		database.DB.Model(&models.Task{}).Where("task_group_id = ?", group.ID).Count(&totalTasks)
 
-		// Count completed tasks for this task group
-		database.DB.Model(&models.Task{}).Where("task_group_id = ? AND status = ?", group.ID, "COMPLETED").Count(&completedTasks)
+		// Count tasks in a "done" status of the group's workflow
+		database.DB.Model(&models.Task{}).Where("task_group_id = ? AND "+database.TaskStatusInCategory, group.ID, models.StatusCategoryDone).Count(&completedTasks)
 
 		// Calculate completion rate
 		completionRate := 0
@@ -102,6 +93,8 @@ func GetTaskGroups(c *gin.Context) {
 			BackgroundColor: group.BackgroundColor,
 			IconColor:       group.IconColor,
 			UserID:          group.UserID,
+			WorkspaceID:     group.WorkspaceID,
+			Role:            taskGroupRole(group, userID),
 			TotalTasks:      totalTasks,
 			CompletionRate:  completionRate,
 		})
@@ -112,29 +105,8 @@ func GetTaskGroups(c *gin.Context) {
 
 // GetTaskGroup retrieves a single task group by ID
 func GetTaskGroup(c *gin.Context) {
-	id := c.Param("id")
-
-	authHeader := c.GetHeader("Authorization")
-	if authHeader == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization token required"})
-		return
-	}
-
-	parts := strings.Split(authHeader, " ")
-	if len(parts) != 2 || parts[0] != "Bearer" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid Authorization header format"})
-		return
-	}
-
-	_, err := utils.ParseJWT(parts[1])
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
-		return
-	}
-
-	var taskGroup models.TaskGroup
-	if err := database.DB.First(&taskGroup, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Task group not found"})
+	taskGroup, ok := findTaskGroup(c, c.Param("id"), models.TaskGroupViewer)
+	if !ok {
 		return
 	}
 
@@ -143,65 +115,73 @@ func GetTaskGroup(c *gin.Context) {
 
 // UpdateTaskGroup updates an existing task group
 func UpdateTaskGroup(c *gin.Context) {
-	id := c.Param("id")
-
-	authHeader := c.GetHeader("Authorization")
-	if authHeader == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization token required"})
+	taskGroup, ok := findTaskGroup(c, c.Param("id"), models.TaskGroupEditor)
+	if !ok {
 		return
 	}
+	before := taskGroup
 
-	parts := strings.Split(authHeader, " ")
-	if len(parts) != 2 || parts[0] != "Bearer" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid Authorization header format"})
-		return
+	// Only the presentation fields can be changed; omitted fields keep their value
+	var request struct {
+		Name            *string `json:"name"`
+		Description     *string `json:"description"`
+		IconData        *int    `json:"icon_data"`
+		BackgroundColor *string `json:"background_color"`
+		IconColor       *string `json:"icon_color"`
 	}
-
-	_, err := utils.ParseJWT(parts[1])
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
 		return
 	}
 
-	var taskGroup models.TaskGroup
-	if err := database.DB.First(&taskGroup, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Task group not found"})
-		return
+	if request.Name != nil {
+		taskGroup.Name = *request.Name
+	}
+	if request.Description != nil {
+		taskGroup.Description = *request.Description
+	}
+	if request.IconData != nil {
+		taskGroup.IconData = *request.IconData
+	}
+	if request.BackgroundColor != nil {
+		taskGroup.BackgroundColor = *request.BackgroundColor
+	}
+	if request.IconColor != nil {
+		taskGroup.IconColor = *request.IconColor
 	}
 
-	if err := c.ShouldBindJSON(&taskGroup); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+	if err := database.DB.Save(&taskGroup).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update task group"})
 		return
 	}
-	database.DB.Save(&taskGroup)
+	if fields := changedTaskGroupFields(before, taskGroup); len(fields) > 0 {
+		recordActivity(c, taskGroup.ID, models.ActivityGroupUpdated, nil, map[string]interface{}{"fields": fields})
+	}
 	c.JSON(http.StatusOK, gin.H{})
 }
 
-// DeleteTaskGroup deletes a task group
+// DeleteTaskGroup deletes a task group with its tasks and everything attached to them
 func DeleteTaskGroup(c *gin.Context) {
-	id := c.Param("id")
-
-	authHeader := c.GetHeader("Authorization")
-	if authHeader == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization token required"})
+	taskGroup, ok := findTaskGroup(c, c.Param("id"), models.TaskGroupOwner)
+	if !ok {
 		return
 	}
 
-	parts := strings.Split(authHeader, " ")
-	if len(parts) != 2 || parts[0] != "Bearer" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid Authorization header format"})
-		return
-	}
+	var memberIDs []uint
+	database.DB.Model(&models.TaskGroupMember{}).Where("task_group_id = ?", taskGroup.ID).Pluck("user_id", &memberIDs)
 
-	_, err := utils.ParseJWT(parts[1])
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		return database.DeleteTaskGroups(tx, []uint{taskGroup.ID}, false)
+	})
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete task group"})
 		return
 	}
+	recordActivity(c, taskGroup.ID, models.ActivityGroupDeleted, nil, map[string]interface{}{"name": taskGroup.Name})
 
-	if err := database.DB.Delete(&models.TaskGroup{}, id).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete task group"})
-		return
+	actor := middleware.CurrentUser(c)
+	for _, memberID := range memberIDs {
+		notifyTaskGroupShared(actor, taskGroup, memberID, fmt.Sprintf("%s deleted the task group %q", actor.FullName, taskGroup.Name))
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Task group deleted successfully"})
@@ -209,38 +189,28 @@ func DeleteTaskGroup(c *gin.Context) {
 
 // GetTasksWithCompletionPercentage get percentage of how much done and tasks for this group
 func GetTasksWithCompletionPercentage(c *gin.Context) {
-	taskGroupID := c.Param("id")
-
-	authHeader := c.GetHeader("Authorization")
-	if authHeader == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization token required"})
-		return
-	}
-
-	parts := strings.Split(authHeader, " ")
-	if len(parts) != 2 || parts[0] != "Bearer" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid Authorization header format"})
-		return
-	}
-
-	_, err := utils.ParseJWT(parts[1])
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+	taskGroup, ok := findTaskGroup(c, c.Param("id"), models.TaskGroupViewer)
+	if !ok {
 		return
 	}
 
 	// Get all tasks for the given task group
 	var tasks []models.Task
-	if err := database.DB.Where("task_group_id = ?", taskGroupID).Find(&tasks).Error; err != nil {
+	if err := database.DB.Where("task_group_id = ?", taskGroup.ID).Find(&tasks).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve tasks"})
 		return
 	}
 
+	done := map[string]bool{}
+	for _, status := range taskGroupStatuses(taskGroup.ID) {
+		done[status.Name] = status.Category == models.StatusCategoryDone
+	}
+
 	// Calculate completion percentage
 	totalTasks := len(tasks)
 	completedTasks := 0
 	for _, task := range tasks {
-		if task.Status == "COMPLETED" {
+		if done[task.Status] {
 			completedTasks++
 		}
 	}
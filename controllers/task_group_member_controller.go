@@ -0,0 +1,277 @@
+package controllers
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"material_todo_go/database"
+	"material_todo_go/middleware"
+	"material_todo_go/models"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// taskGroupRoleRank orders the roles so a required role also admits the ones above it
+var taskGroupRoleRank = map[string]int{
+	models.TaskGroupViewer: 1,
+	models.TaskGroupEditor: 2,
+	models.TaskGroupOwner:  3,
+}
+
+// workspaceTaskGroupRoles maps workspace roles to the role they grant on the workspace's task groups
+var workspaceTaskGroupRoles = map[string]string{
+	models.WorkspaceRoleViewer: models.TaskGroupViewer,
+	models.WorkspaceRoleMember: models.TaskGroupEditor,
+	models.WorkspaceRoleAdmin:  models.TaskGroupOwner,
+	models.WorkspaceRoleOwner:  models.TaskGroupOwner,
+}
+
+// taskGroupRole returns the role of the user on the task group, or "" without access.
+// It is the highest of ownership, a direct membership and the user's role in the group's workspace.
+func taskGroupRole(taskGroup models.TaskGroup, userID uint) string {
+	if taskGroup.ID == 0 {
+		return ""
+	}
+	if taskGroup.UserID == userID {
+		return models.TaskGroupOwner
+	}
+
+	role := ""
+	var member models.TaskGroupMember
+	if err := database.DB.Where("task_group_id = ? AND user_id = ?", taskGroup.ID, userID).First(&member).Error; err == nil {
+		role = member.Role
+	}
+
+	var workspaceMember models.WorkspaceMember
+	if err := database.DB.Where("workspace_id = ? AND user_id = ?", taskGroup.WorkspaceID, userID).First(&workspaceMember).Error; err == nil {
+		if inherited := workspaceTaskGroupRoles[workspaceMember.Role]; taskGroupRoleRank[inherited] > taskGroupRoleRank[role] {
+			role = inherited
+		}
+	}
+	return role
+}
+
+// sharedTaskGroupIDs selects the IDs of the task groups shared with the user directly
+func sharedTaskGroupIDs(userID uint) *gorm.DB {
+	return database.DB.Model(&models.TaskGroupMember{}).Select("task_group_id").Where("user_id = ?", userID)
+}
+
+// accessibleTaskGroupIDs selects the IDs of the task groups the user owns, is a member of, or sees through a workspace
+func accessibleTaskGroupIDs(userID uint) *gorm.DB {
+	workspaces := database.DB.Model(&models.WorkspaceMember{}).Select("workspace_id").Where("user_id = ?", userID)
+	return database.DB.Model(&models.TaskGroup{}).Select("id").
+		Where("user_id = ? OR id IN (?) OR workspace_id IN (?)", userID, sharedTaskGroupIDs(userID), workspaces)
+}
+
+// workspaceTaskGroupIDs selects the task groups listed in the active workspace: its own groups and,
+// in the personal workspace, the groups other users shared directly
+func workspaceTaskGroupIDs(c *gin.Context) *gorm.DB {
+	workspace := middleware.CurrentWorkspace(c)
+	query := database.DB.Model(&models.TaskGroup{}).Select("id")
+	if workspace.Personal {
+		return query.Where("workspace_id = ? OR id IN (?)", workspace.ID, sharedTaskGroupIDs(middleware.CurrentUser(c).ID))
+	}
+	return query.Where("workspace_id = ?", workspace.ID)
+}
+
+// checkTaskGroupRole responds 404 when the user cannot see the group and 403 when their role is below the required one
+func checkTaskGroupRole(c *gin.Context, taskGroup models.TaskGroup, required string, notFound string) bool {
+	role := taskGroupRole(taskGroup, middleware.CurrentUser(c).ID)
+	if role == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": notFound})
+		return false
+	}
+	if taskGroupRoleRank[role] < taskGroupRoleRank[required] {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You need the " + required + " role on this task group"})
+		return false
+	}
+	return true
+}
+
+// findTaskGroup loads a task group the authenticated user holds at least the required role on
+func findTaskGroup(c *gin.Context, id interface{}, required string) (models.TaskGroup, bool) {
+	var taskGroup models.TaskGroup
+	database.DB.Where("id = ?", id).Limit(1).Find(&taskGroup)
+	return taskGroup, checkTaskGroupRole(c, taskGroup, required, "Task group not found")
+}
+
+// findTask loads a task with its group, requiring at least the given role on the group
+func findTask(c *gin.Context, id interface{}, required string) (models.Task, bool) {
+	var task models.Task
+	database.DB.Preload("TaskGroup").Preload("Assignees.User").Where("id = ?", id).Limit(1).Find(&task)
+	return task, checkTaskGroupRole(c, task.TaskGroup, required, "Task not found")
+}
+
+// memberResponse describes a user with access to a task group
+func memberResponse(user models.User, role string) gin.H {
+	return gin.H{
+		"user_id":   user.ID,
+		"full_name": user.FullName,
+		"email":     user.Email,
+		"image":     user.Image,
+		"role":      role,
+	}
+}
+
+// GetTaskGroupMembers lists the owner and members of a task group
+func GetTaskGroupMembers(c *gin.Context) {
+	taskGroup, ok := findTaskGroup(c, c.Param("id"), models.TaskGroupViewer)
+	if !ok {
+		return
+	}
+
+	response := []gin.H{}
+	var owner models.User
+	if err := database.DB.First(&owner, taskGroup.UserID).Error; err == nil {
+		response = append(response, memberResponse(owner, models.TaskGroupOwner))
+	}
+
+	var members []models.TaskGroupMember
+	database.DB.Where("task_group_id = ?", taskGroup.ID).Order("created_at").Find(&members)
+	for _, member := range members {
+		var user models.User
+		if err := database.DB.First(&user, member.UserID).Error; err != nil {
+			continue
+		}
+		response = append(response, memberResponse(user, member.Role))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// validMemberRole reports whether a role can be given to a member; ownership cannot be shared
+func validMemberRole(role string) bool {
+	return role == models.TaskGroupViewer || role == models.TaskGroupEditor
+}
+
+// AddTaskGroupMember shares a task group with another user by email
+func AddTaskGroupMember(c *gin.Context) {
+	taskGroup, ok := findTaskGroup(c, c.Param("id"), models.TaskGroupOwner)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		Email string `json:"email"`
+		Role  string `json:"role"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil || request.Email == "" || !validMemberRole(request.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Email and a role of viewer or editor are required"})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.Where("LOWER(email) = ?", strings.ToLower(request.Email)).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if user.ID == taskGroup.UserID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The owner already has access"})
+		return
+	}
+
+	var existing int64
+	database.DB.Model(&models.TaskGroupMember{}).Where("task_group_id = ? AND user_id = ?", taskGroup.ID, user.ID).Count(&existing)
+	if existing > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "User is already a member"})
+		return
+	}
+
+	member := models.TaskGroupMember{TaskGroupID: taskGroup.ID, UserID: user.ID, Role: request.Role}
+	if err := database.DB.Create(&member).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add member"})
+		return
+	}
+
+	recordActivity(c, taskGroup.ID, models.ActivityMemberAdded, nil, map[string]interface{}{"user_id": user.ID, "role": member.Role})
+
+	actor := middleware.CurrentUser(c)
+	notifyTaskGroupShared(actor, taskGroup, user.ID,
+		fmt.Sprintf("%s shared the task group %q with you as %s", actor.FullName, taskGroup.Name, member.Role))
+
+	c.JSON(http.StatusCreated, memberResponse(user, member.Role))
+}
+
+// UpdateTaskGroupMember changes the role of a member
+func UpdateTaskGroupMember(c *gin.Context) {
+	taskGroup, ok := findTaskGroup(c, c.Param("id"), models.TaskGroupOwner)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		Role string `json:"role"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil || !validMemberRole(request.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Role must be viewer or editor"})
+		return
+	}
+
+	result := database.DB.Model(&models.TaskGroupMember{}).
+		Where("task_group_id = ? AND user_id = ?", taskGroup.ID, c.Param("user_id")).
+		Update("role", request.Role)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update member"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+		return
+	}
+
+	if memberID, err := strconv.ParseUint(c.Param("user_id"), 10, 64); err == nil {
+		recordActivity(c, taskGroup.ID, models.ActivityMemberRole, nil, map[string]interface{}{"user_id": memberID, "role": request.Role})
+
+		actor := middleware.CurrentUser(c)
+		notifyTaskGroupShared(actor, taskGroup, uint(memberID),
+			fmt.Sprintf("%s changed your role in the task group %q to %s", actor.FullName, taskGroup.Name, request.Role))
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// RemoveTaskGroupMember removes a member; the owner can remove anyone and members can leave
+func RemoveTaskGroupMember(c *gin.Context) {
+	userID := middleware.CurrentUser(c).ID
+
+	required := models.TaskGroupOwner
+	if c.Param("user_id") == strconv.FormatUint(uint64(userID), 10) {
+		required = models.TaskGroupViewer
+	}
+
+	taskGroup, ok := findTaskGroup(c, c.Param("id"), required)
+	if !ok {
+		return
+	}
+
+	result := database.DB.Where("task_group_id = ? AND user_id = ?", taskGroup.ID, c.Param("user_id")).Delete(&models.TaskGroupMember{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove member"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+		return
+	}
+
+	memberID, _ := strconv.ParseUint(c.Param("user_id"), 10, 64)
+	if err := unassignWithoutAccess(taskGroup, uint(memberID), userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unassign member"})
+		return
+	}
+	recordActivity(c, taskGroup.ID, models.ActivityMemberRemoved, nil, map[string]interface{}{"user_id": memberID})
+
+	// Members leaving notify the owner, members removed by the owner are told themselves
+	actor := middleware.CurrentUser(c)
+	if uint(memberID) != userID {
+		notifyTaskGroupShared(actor, taskGroup, uint(memberID),
+			fmt.Sprintf("%s removed you from the task group %q", actor.FullName, taskGroup.Name))
+	} else {
+		notifyTaskGroupShared(actor, taskGroup, taskGroup.UserID,
+			fmt.Sprintf("%s left the task group %q", actor.FullName, taskGroup.Name))
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
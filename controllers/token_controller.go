@@ -0,0 +1,177 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+	"material_todo_go/config"
+	"material_todo_go/database"
+	"material_todo_go/middleware"
+	"material_todo_go/models"
+	"material_todo_go/utils"
+	"net/http"
+	"time"
+)
+
+// issueTokens creates an access token and a refresh token for the session
+func issueTokens(user models.User, sessionID uint) (gin.H, error) {
+	accessToken, err := utils.GenerateJWT(user.Email, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := utils.GenerateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	record := models.RefreshToken{
+		UserID:    user.ID,
+		SessionID: sessionID,
+		TokenHash: utils.HashToken(refreshToken),
+		ExpiresAt: time.Now().Add(config.RefreshTokenTTL),
+	}
+	if err := database.DB.Create(&record).Error; err != nil {
+		return nil, err
+	}
+
+	return gin.H{
+		"token":         accessToken,
+		"refresh_token": refreshToken,
+		"expires_in":    int(config.AccessTokenTTL.Seconds()),
+	}, nil
+}
+
+// revokeAccessToken adds the token's jti to the revocation store until it expires
+func revokeAccessToken(userID uint, claims *utils.Claims) error {
+	// Expired entries are no longer needed since ParseJWT rejects them anyway
+	database.DB.Where("expires_at < ?", time.Now()).Delete(&models.RevokedToken{})
+
+	return database.DB.Create(&models.RevokedToken{
+		JTI:       claims.ID,
+		UserID:    userID,
+		ExpiresAt: claims.ExpiresAt.Time,
+	}).Error
+}
+
+// invalidateUserTokens rejects every access token issued so far and revokes all sessions, refresh tokens
+// and personal access tokens of the user
+func invalidateUserTokens(userID uint) error {
+	if err := database.DB.Model(&models.User{}).Where("id = ?", userID).Update("tokens_valid_after", time.Now()).Error; err != nil {
+		return err
+	}
+
+	if err := database.DB.Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error; err != nil {
+		return err
+	}
+
+	if err := database.DB.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error; err != nil {
+		return err
+	}
+
+	return database.DB.Model(&models.PersonalAccessToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// refreshTokenProblem reports why a stored refresh token cannot be exchanged, and whether it is being replayed
+func refreshTokenProblem(record models.RefreshToken, now time.Time) (message string, reused bool) {
+	// A token that was already rotated or revoked is being replayed, so the whole session is compromised
+	if record.UsedAt != nil || record.RevokedAt != nil {
+		return "Refresh token reuse detected", true
+	}
+
+	if now.After(record.ExpiresAt) {
+		return "Refresh token expired", false
+	}
+
+	return "", false
+}
+
+// RefreshToken exchanges a refresh token for a new access and refresh token pair
+func RefreshToken(c *gin.Context) {
+	var request struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil || request.RefreshToken == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Refresh token is required"})
+		return
+	}
+
+	var record models.RefreshToken
+	if err := database.DB.Where("token_hash = ?", utils.HashToken(request.RefreshToken)).First(&record).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		return
+	}
+
+	if message, reused := refreshTokenProblem(record, time.Now()); message != "" {
+		if reused {
+			revokeSession(record.SessionID)
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": message})
+		return
+	}
+
+	// Consume the token; losing this race also means it was presented twice
+	result := database.DB.Model(&models.RefreshToken{}).Where("id = ? AND used_at IS NULL", record.ID).Update("used_at", time.Now())
+	if result.Error != nil || result.RowsAffected == 0 {
+		revokeSession(record.SessionID)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token reuse detected"})
+		return
+	}
+
+	var session models.Session
+	if err := database.DB.Where("id = ? AND revoked_at IS NULL", record.SessionID).First(&session).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Session has been revoked"})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, record.UserID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	if user.Disabled {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account is disabled"})
+		return
+	}
+
+	if user.PasswordResetRequired {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Password reset required"})
+		return
+	}
+
+	database.DB.Model(&session).Update("last_seen_at", time.Now())
+
+	tokens, err := issueTokens(user, session.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// Logout ends the current session, revoking its access and refresh tokens
+func Logout(c *gin.Context) {
+	if err := revokeSession(middleware.CurrentClaims(c).SessionID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// LogoutAll revokes every access, refresh and personal access token of the current user on all devices
+func LogoutAll(c *gin.Context) {
+	if err := invalidateUserTokens(middleware.CurrentUser(c).ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
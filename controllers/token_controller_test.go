@@ -0,0 +1,34 @@
+package controllers
+
+import (
+	"material_todo_go/models"
+	"testing"
+	"time"
+)
+
+func TestRefreshTokenProblem(t *testing.T) {
+	now := time.Now()
+	earlier := now.Add(-time.Minute)
+
+	tests := []struct {
+		name        string
+		record      models.RefreshToken
+		wantMessage string
+		wantReused  bool
+	}{
+		{"unused and valid", models.RefreshToken{ExpiresAt: now.Add(time.Hour)}, "", false},
+		{"expired", models.RefreshToken{ExpiresAt: earlier}, "Refresh token expired", false},
+		{"already rotated", models.RefreshToken{ExpiresAt: now.Add(time.Hour), UsedAt: &earlier}, "Refresh token reuse detected", true},
+		{"revoked", models.RefreshToken{ExpiresAt: now.Add(time.Hour), RevokedAt: &earlier}, "Refresh token reuse detected", true},
+		// Replaying an old token still revokes the session once it has expired
+		{"rotated and expired", models.RefreshToken{ExpiresAt: earlier, UsedAt: &earlier}, "Refresh token reuse detected", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message, reused := refreshTokenProblem(tt.record, now)
+			if message != tt.wantMessage || reused != tt.wantReused {
+				t.Errorf("refreshTokenProblem = (%q, %t), want (%q, %t)", message, reused, tt.wantMessage, tt.wantReused)
+			}
+		})
+	}
+}
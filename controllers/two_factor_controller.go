@@ -0,0 +1,215 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+	"material_todo_go/audit"
+	"material_todo_go/config"
+	"material_todo_go/database"
+	"material_todo_go/limiter"
+	"material_todo_go/middleware"
+	"material_todo_go/models"
+	"material_todo_go/utils"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const recoveryCodeCount = 10
+
+// verifyTOTP checks a code and consumes its time step so the same code cannot be replayed
+func verifyTOTP(user models.User, code string) bool {
+	step, ok := utils.ValidateTOTP(user.TOTPSecret, strings.TrimSpace(code), time.Now())
+	if !ok {
+		return false
+	}
+
+	result := database.DB.Model(&models.User{}).
+		Where("id = ? AND totp_last_counter < ?", user.ID, step).
+		Update("totp_last_counter", step)
+	return result.Error == nil && result.RowsAffected == 1
+}
+
+// useRecoveryCode consumes one unused recovery code of the user
+func useRecoveryCode(userID uint, code string) bool {
+	hash := utils.HashToken(strings.ToLower(strings.TrimSpace(code)))
+	result := database.DB.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hash).
+		Update("used_at", time.Now())
+	return result.Error == nil && result.RowsAffected == 1
+}
+
+// generateRecoveryCodes replaces the user's recovery codes and returns the new plain codes
+func generateRecoveryCodes(userID uint) ([]string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	records := make([]models.RecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := utils.GenerateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		records = append(records, models.RecoveryCode{UserID: userID, CodeHash: utils.HashToken(code)})
+	}
+
+	if err := database.DB.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+	if err := database.DB.Create(&records).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// EnrollTwoFactor creates a new TOTP secret for the user, enabled only after ConfirmTwoFactor
+func EnrollTwoFactor(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	if user.TOTPEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate secret"})
+		return
+	}
+
+	if err := database.DB.Model(&models.User{}).Where("id = ?", user.ID).
+		Updates(map[string]interface{}{"totp_secret": secret, "totp_last_counter": 0}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save secret"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret":      secret,
+		"otpauth_uri": utils.TOTPURI(config.AppName, user.Email, secret),
+	})
+}
+
+// ConfirmTwoFactor enables two-factor authentication with a first code and returns the recovery codes
+func ConfirmTwoFactor(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	var request struct {
+		Code string `json:"code"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil || request.Code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Code is required"})
+		return
+	}
+
+	if user.TOTPEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+	if user.TOTPSecret == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Start enrollment first"})
+		return
+	}
+
+	if !verifyTOTP(user, request.Code) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid code"})
+		return
+	}
+
+	codes, err := generateRecoveryCodes(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate recovery codes"})
+		return
+	}
+
+	if err := database.DB.Model(&models.User{}).Where("id = ?", user.ID).Update("totp_enabled", true).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable two-factor authentication"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// DisableTwoFactor turns two-factor authentication off after checking the password
+func DisableTwoFactor(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	var request struct {
+		Password string `json:"password"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil || request.Password == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Password is required"})
+		return
+	}
+
+	if !utils.CheckPasswordHash(request.Password, user.Password) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid password"})
+		return
+	}
+
+	if err := database.DB.Model(&models.User{}).Where("id = ?", user.ID).
+		Updates(map[string]interface{}{"totp_enabled": false, "totp_secret": "", "totp_last_counter": 0}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable two-factor authentication"})
+		return
+	}
+	database.DB.Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{})
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// LoginTwoFactor completes a login with the challenge token and a TOTP or recovery code
+func LoginTwoFactor(c *gin.Context) {
+	var request struct {
+		ChallengeToken string `json:"challenge_token"`
+		Code           string `json:"code"`
+		RecoveryCode   string `json:"recovery_code"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil || request.ChallengeToken == "" || (request.Code == "" && request.RecoveryCode == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Challenge token and code are required"})
+		return
+	}
+
+	claims, err := utils.ParseChallengeJWT(request.ChallengeToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired challenge"})
+		return
+	}
+
+	mfaKey := "mfa:account:" + strings.ToLower(claims.Email)
+	if !checkRateLimit(c, limiter.Accounts, mfaKey) {
+		return
+	}
+
+	var user models.User
+	if err := database.DB.Where("email = ?", claims.Email).First(&user).Error; err != nil || !user.TOTPEnabled {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired challenge"})
+		return
+	}
+
+	valid := false
+	if request.Code != "" {
+		valid = verifyTOTP(user, request.Code)
+	} else {
+		valid = useRecoveryCode(user.ID, request.RecoveryCode)
+	}
+
+	if !valid {
+		audit.Record(c, audit.Entry{Event: audit.EventLoginFailed, SubjectID: user.ID, Email: user.Email,
+			Metadata: map[string]interface{}{"reason": "invalid_second_factor"}})
+		if _, wait, err := limiter.Accounts.Fail(mfaKey); err == nil && wait > 0 {
+			c.Header("Retry-After", limiter.RetryAfter(wait))
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid code"})
+		return
+	}
+	limiter.Accounts.Reset(mfaKey)
+
+	// The account may have been disabled or sent to a password reset since the first factor
+	if !signInAllowed(c, user) {
+		return
+	}
+
+	// The challenge is single-use
+	if err := revokeAccessToken(user.ID, claims); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to complete login"})
+		return
+	}
+
+	loginWithNewSession(c, user)
+}
@@ -2,8 +2,9 @@ package controllers
 
 import (
 	"github.com/gin-gonic/gin"
+	"material_todo_go/audit"
 	"material_todo_go/database"
-	"material_todo_go/models"
+	"material_todo_go/middleware"
 	"material_todo_go/utils"
 	"net/http"
 	_ "os"
@@ -13,75 +14,24 @@ import (
 )
 
 func GetUserInformation(c *gin.Context) {
-	// Extract the Authorization header
-	authHeader := c.GetHeader("Authorization")
-	if authHeader == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization token required"})
-		return
-	}
-
-	// Split "Bearer <TOKEN>" and extract only the token
-	parts := strings.Split(authHeader, " ")
-	if len(parts) != 2 || parts[0] != "Bearer" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid Authorization header format"})
-		return
-	}
-
-	tokenString := parts[1] // Extract the actual JWT
-
-	// Parse JWT token and get email
-	email, err := utils.ParseJWT(tokenString)
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
-		return
-	}
-
-	// Find user by email
-	var user models.User
-	if err := database.DB.Where("email = ?", email).First(&user).Error; err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
-		return
-	}
+	user := middleware.CurrentUser(c)
 
 	// Return user data (excluding password)
 	c.JSON(http.StatusOK, gin.H{
-		"id":        user.ID,
-		"full_name": user.FullName,
-		"email":     user.Email,
-		"image":     user.Image,
+		"id":                    user.ID,
+		"full_name":             user.FullName,
+		"email":                 user.Email,
+		"image":                 user.Image,
+		"email_verified":        user.EmailVerified,
+		"role":                  user.Role,
+		"two_factor_enabled":    user.TOTPEnabled,
+		"deletion_scheduled_at": user.DeletionScheduledAt,
+		"active_workspace_id":   user.ActiveWorkspaceID,
 	})
 }
 
 func UpdateUser(c *gin.Context) {
-	// Extract Authorization token
-	authHeader := c.GetHeader("Authorization")
-	if authHeader == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization token required"})
-		return
-	}
-
-	// Split "Bearer <TOKEN>" and extract only the token
-	parts := strings.Split(authHeader, " ")
-	if len(parts) != 2 || parts[0] != "Bearer" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid Authorization header format"})
-		return
-	}
-
-	tokenString := parts[1] // Extract the actual JWT
-
-	// Parse JWT and get email
-	email, err := utils.ParseJWT(tokenString)
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
-		return
-	}
-
-	// Find user by email
-	var user models.User
-	if err := database.DB.Where("email = ?", email).First(&user).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
-		return
-	}
+	user := middleware.CurrentUser(c)
 
 	// Define variables for optional fields
 	var newFullName string
@@ -132,6 +82,55 @@ func UpdateUser(c *gin.Context) {
 		return
 	}
 
+	audit.Record(c, audit.Entry{Event: audit.EventProfileUpdated, ActorID: user.ID, SubjectID: user.ID,
+		Metadata: map[string]interface{}{"full_name_changed": newFullName != "", "image_changed": filePath != ""}})
+
 	// Return updated user data
 	c.JSON(http.StatusOK, gin.H{})
 }
+
+// ChangePassword sets a new password after checking the current one and signs out the other sessions
+func ChangePassword(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	var request struct {
+		CurrentPassword string `json:"current_password"`
+		NewPassword     string `json:"new_password"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil || request.CurrentPassword == "" || request.NewPassword == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Current and new password are required"})
+		return
+	}
+
+	if !utils.CheckPasswordHash(request.CurrentPassword, user.Password) {
+		audit.Record(c, audit.Entry{Event: audit.EventPasswordChangeFailed, ActorID: user.ID, SubjectID: user.ID,
+			Metadata: map[string]interface{}{"reason": "invalid_current_password"}})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Current password is incorrect"})
+		return
+	}
+
+	if err := utils.ValidatePassword(request.NewPassword); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	hashedPassword, err := utils.HashPassword(request.NewPassword)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+
+	if err := database.DB.Model(&user).Update("password", hashedPassword).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update password"})
+		return
+	}
+
+	if err := revokeOtherSessions(user.ID, middleware.CurrentClaims(c).SessionID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke other sessions"})
+		return
+	}
+
+	audit.Record(c, audit.Entry{Event: audit.EventPasswordChanged, ActorID: user.ID, SubjectID: user.ID})
+
+	c.JSON(http.StatusOK, gin.H{})
+}
@@ -0,0 +1,301 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"material_todo_go/database"
+	"material_todo_go/models"
+	"net/http"
+	"strings"
+)
+
+// taskGroupStatuses returns the workflow statuses of a task group in order
+func taskGroupStatuses(taskGroupID uint) []models.TaskGroupStatus {
+	var statuses []models.TaskGroupStatus
+	database.DB.Where("task_group_id = ?", taskGroupID).Order("position").Find(&statuses)
+	return statuses
+}
+
+// statusCategory returns the category of a status in a task group, or "" if the group has no such status
+func statusCategory(taskGroupID uint, name string) string {
+	var status models.TaskGroupStatus
+	database.DB.Where("task_group_id = ? AND name = ?", taskGroupID, name).Limit(1).Find(&status)
+	return status.Category
+}
+
+// checkTaskStatus validates that a task of the group may move from one status to another, responding 400
+// for unknown statuses or disallowed transitions and 409 when the WIP limit is reached. from is "" for
+// new tasks and tasks arriving from another group.
+func checkTaskStatus(c *gin.Context, taskGroupID, taskID uint, from, to string) bool {
+	var status models.TaskGroupStatus
+	if err := database.DB.Where("task_group_id = ? AND name = ?", taskGroupID, to).First(&status).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unknown status %q for this task group", to)})
+		return false
+	}
+
+	if from == to {
+		return true
+	}
+
+	if from != "" {
+		var transitions []models.TaskGroupTransition
+		database.DB.Where("task_group_id = ?", taskGroupID).Find(&transitions)
+		if !transitionAllowed(transitions, from, to) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Moving from %q to %q is not allowed", from, to)})
+			return false
+		}
+	}
+
+	if status.WIPLimit > 0 {
+		var inProgress int64
+		database.DB.Model(&models.Task{}).Where("task_group_id = ? AND status = ? AND id <> ?", taskGroupID, to, taskID).Count(&inProgress)
+		if wipLimitReached(status, inProgress) {
+			c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Status %q has reached its limit of %d tasks", to, status.WIPLimit)})
+			return false
+		}
+	}
+	return true
+}
+
+// transitionAllowed reports whether a task may move between statuses. New tasks, tasks that keep
+// their status and groups without transitions are never restricted.
+func transitionAllowed(transitions []models.TaskGroupTransition, from, to string) bool {
+	if from == "" || from == to || len(transitions) == 0 {
+		return true
+	}
+	for _, transition := range transitions {
+		if transition.FromStatus == from && transition.ToStatus == to {
+			return true
+		}
+	}
+	return false
+}
+
+// wipLimitReached reports whether a status is full, given the number of other tasks already in it
+func wipLimitReached(status models.TaskGroupStatus, tasks int64) bool {
+	return status.WIPLimit > 0 && tasks >= int64(status.WIPLimit)
+}
+
+// workflowResponse describes the statuses, with their task counts, and transitions of a task group
+func workflowResponse(taskGroupID uint) gin.H {
+	statuses := []gin.H{}
+	for _, status := range taskGroupStatuses(taskGroupID) {
+		var tasks int64
+		database.DB.Model(&models.Task{}).Where("task_group_id = ? AND status = ?", taskGroupID, status.Name).Count(&tasks)
+		statuses = append(statuses, gin.H{
+			"id":         status.ID,
+			"name":       status.Name,
+			"position":   status.Position,
+			"category":   status.Category,
+			"wip_limit":  status.WIPLimit,
+			"task_count": tasks,
+		})
+	}
+
+	transitions := []models.TaskGroupTransition{}
+	database.DB.Where("task_group_id = ?", taskGroupID).Order("id").Find(&transitions)
+
+	return gin.H{"statuses": statuses, "transitions": transitions}
+}
+
+// GetTaskGroupWorkflow returns the workflow of a task group
+func GetTaskGroupWorkflow(c *gin.Context) {
+	taskGroup, ok := findTaskGroup(c, c.Param("id"), models.TaskGroupViewer)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, workflowResponse(taskGroup.ID))
+}
+
+// workflowError rejects a workflow update from inside its transaction with the given HTTP status
+type workflowError struct {
+	status  int
+	message string
+}
+
+func (e *workflowError) Error() string {
+	return e.message
+}
+
+// UpdateTaskGroupWorkflow replaces the workflow of a task group. Statuses keep their order in the request;
+// listing an existing status by id keeps that id, and giving it a new name renames it together with its tasks.
+// Statuses still used by tasks cannot be removed.
+func UpdateTaskGroupWorkflow(c *gin.Context) {
+	taskGroup, ok := findTaskGroup(c, c.Param("id"), models.TaskGroupEditor)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		Statuses []struct {
+			ID       uint   `json:"id"`
+			Name     string `json:"name"`
+			Category string `json:"category"`
+			WIPLimit int    `json:"wip_limit"`
+		} `json:"statuses"`
+		Transitions []models.TaskGroupTransition `json:"transitions"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil || len(request.Statuses) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At least one status is required"})
+		return
+	}
+
+	names := map[string]bool{}
+	newNames := map[string]bool{}
+	hasDone := false
+	for i := range request.Statuses {
+		status := &request.Statuses[i]
+		status.Name = strings.TrimSpace(status.Name)
+		if status.Name == "" || names[status.Name] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Status names must be unique and not empty"})
+			return
+		}
+		names[status.Name] = true
+		if status.ID == 0 {
+			newNames[status.Name] = true
+		}
+
+		switch status.Category {
+		case models.StatusCategoryTodo, models.StatusCategoryInProgress:
+		case models.StatusCategoryDone:
+			hasDone = true
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Category must be todo, in_progress or done"})
+			return
+		}
+
+		if status.WIPLimit < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "WIP limits cannot be negative"})
+			return
+		}
+	}
+	if !hasDone {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At least one status must be in the done category"})
+		return
+	}
+
+	for _, transition := range request.Transitions {
+		if !names[transition.FromStatus] || !names[transition.ToStatus] || transition.FromStatus == transition.ToStatus {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Transitions must connect two different statuses of the workflow"})
+			return
+		}
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		// Concurrent updates of the same workflow wait for each other
+		var current []models.TaskGroupStatus
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("task_group_id = ?", taskGroup.ID).Find(&current).Error; err != nil {
+			return err
+		}
+		existing := map[uint]models.TaskGroupStatus{}
+		for _, status := range current {
+			existing[status.ID] = status
+		}
+
+		kept := map[uint]bool{}
+		for _, status := range request.Statuses {
+			if status.ID == 0 {
+				continue
+			}
+			if _, ok := existing[status.ID]; !ok || kept[status.ID] {
+				return &workflowError{http.StatusBadRequest, fmt.Sprintf("Status %d does not belong to this task group or is listed twice", status.ID)}
+			}
+			kept[status.ID] = true
+		}
+
+		// Removed statuses must not be in use, unless a new status takes over their name
+		for _, status := range current {
+			if kept[status.ID] {
+				continue
+			}
+			if !newNames[status.Name] {
+				var inUse int64
+				if err := tx.Model(&models.Task{}).Where("task_group_id = ? AND status = ?", taskGroup.ID, status.Name).Count(&inUse).Error; err != nil {
+					return err
+				}
+				if inUse > 0 {
+					return &workflowError{http.StatusConflict, fmt.Sprintf("Status %q is still used by %d tasks", status.Name, inUse)}
+				}
+			}
+			if err := tx.Delete(&status).Error; err != nil {
+				return err
+			}
+		}
+
+		// Tasks are renamed in a single statement so that swapped or chained names do not merge statuses
+		var cases []string
+		var args []interface{}
+		var renamed []string
+		for _, status := range request.Statuses {
+			if previous, ok := existing[status.ID]; ok && previous.Name != status.Name {
+				cases = append(cases, "WHEN ? THEN ?")
+				args = append(args, previous.Name, status.Name)
+				renamed = append(renamed, previous.Name)
+
+				// Free the old name first, since another status may be taking it over
+				if err := tx.Model(&previous).Update("name", fmt.Sprintf("\t#%d", previous.ID)).Error; err != nil {
+					return err
+				}
+			}
+		}
+		if len(renamed) > 0 {
+			if err := tx.Model(&models.Task{}).Unscoped().Where("task_group_id = ? AND status IN ?", taskGroup.ID, renamed).
+				Update("status", gorm.Expr("CASE status "+strings.Join(cases, " ")+" END", args...)).Error; err != nil {
+				return err
+			}
+		}
+
+		// Kept statuses are updated in place so their ids stay valid for clients
+		for position, status := range request.Statuses {
+			if status.ID != 0 {
+				if err := tx.Model(&models.TaskGroupStatus{}).Where("id = ?", status.ID).Updates(map[string]interface{}{
+					"name":      status.Name,
+					"position":  position,
+					"category":  status.Category,
+					"wip_limit": status.WIPLimit,
+				}).Error; err != nil {
+					return err
+				}
+				continue
+			}
+			if err := tx.Create(&models.TaskGroupStatus{
+				TaskGroupID: taskGroup.ID,
+				Name:        status.Name,
+				Position:    position,
+				Category:    status.Category,
+				WIPLimit:    status.WIPLimit,
+			}).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Where("task_group_id = ?", taskGroup.ID).Delete(&models.TaskGroupTransition{}).Error; err != nil {
+			return err
+		}
+		for _, transition := range request.Transitions {
+			transition.ID = 0
+			transition.TaskGroupID = taskGroup.ID
+			if err := tx.Create(&transition).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	var rejected *workflowError
+	if errors.As(err, &rejected) {
+		c.JSON(rejected.status, gin.H{"error": rejected.message})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update workflow"})
+		return
+	}
+
+	recordActivity(c, taskGroup.ID, models.ActivityGroupUpdated, nil, map[string]interface{}{"fields": []string{"workflow"}})
+
+	c.JSON(http.StatusOK, workflowResponse(taskGroup.ID))
+}
@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"material_todo_go/models"
+	"testing"
+)
+
+func TestTransitionAllowed(t *testing.T) {
+	transitions := []models.TaskGroupTransition{
+		{FromStatus: "TODO", ToStatus: "IN_PROGRESS"},
+		{FromStatus: "IN_PROGRESS", ToStatus: "REVIEW"},
+		{FromStatus: "REVIEW", ToStatus: "IN_PROGRESS"},
+		{FromStatus: "REVIEW", ToStatus: "DONE"},
+	}
+
+	tests := []struct {
+		name        string
+		transitions []models.TaskGroupTransition
+		from        string
+		to          string
+		want        bool
+	}{
+		{"listed transition", transitions, "TODO", "IN_PROGRESS", true},
+		{"listed the other way round", transitions, "REVIEW", "IN_PROGRESS", true},
+		{"not listed", transitions, "TODO", "DONE", false},
+		{"reverse is not implied", transitions, "IN_PROGRESS", "TODO", false},
+		{"unchanged status", transitions, "DONE", "DONE", true},
+		{"new task", transitions, "", "DONE", true},
+		{"group without transitions", nil, "TODO", "DONE", true},
+		{"names are case sensitive", transitions, "todo", "IN_PROGRESS", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := transitionAllowed(tt.transitions, tt.from, tt.to); got != tt.want {
+				t.Errorf("transitionAllowed(%q, %q) = %t, want %t", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWIPLimitReached(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit int
+		tasks int64
+		want  bool
+	}{
+		{"no limit", 0, 100, false},
+		{"below the limit", 3, 2, false},
+		{"at the limit", 3, 3, true},
+		{"over the limit", 3, 5, true},
+		{"empty status with a limit", 1, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := models.TaskGroupStatus{Name: "IN_PROGRESS", WIPLimit: tt.limit}
+			if got := wipLimitReached(status, tt.tasks); got != tt.want {
+				t.Errorf("wipLimitReached(limit %d, %d tasks) = %t, want %t", tt.limit, tt.tasks, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,413 @@
+package controllers
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"log"
+	"material_todo_go/database"
+	"material_todo_go/mailer"
+	"material_todo_go/middleware"
+	"material_todo_go/models"
+	"material_todo_go/utils"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const workspaceInvitationTTL = 7 * 24 * time.Hour
+
+// findWorkspace loads a workspace the authenticated user belongs to with at least the required role.
+// Workspaces they do not belong to respond 404, a role that is too low responds 403.
+func findWorkspace(c *gin.Context, required string) (models.Workspace, string, bool) {
+	var workspace models.Workspace
+	var member models.WorkspaceMember
+	if err := database.DB.Where("workspace_id = ? AND user_id = ?", c.Param("id"), middleware.CurrentUser(c).ID).First(&member).Error; err != nil ||
+		database.DB.First(&workspace, member.WorkspaceID).Error != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Workspace not found"})
+		return workspace, "", false
+	}
+
+	if !middleware.WorkspaceRoleAtLeast(member.Role, required) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You need the " + required + " role in this workspace"})
+		return workspace, member.Role, false
+	}
+	return workspace, member.Role, true
+}
+
+// validWorkspaceRole reports whether a role can be given to a member; there is only one owner
+func validWorkspaceRole(role string) bool {
+	return role == models.WorkspaceRoleViewer || role == models.WorkspaceRoleMember || role == models.WorkspaceRoleAdmin
+}
+
+// GetWorkspaces lists the workspaces of the authenticated user with their role
+func GetWorkspaces(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	var members []models.WorkspaceMember
+	if err := database.DB.Where("user_id = ?", user.ID).Order("created_at").Find(&members).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve workspaces"})
+		return
+	}
+
+	response := []gin.H{}
+	for _, member := range members {
+		var workspace models.Workspace
+		if err := database.DB.First(&workspace, member.WorkspaceID).Error; err != nil {
+			continue
+		}
+		response = append(response, gin.H{
+			"id":       workspace.ID,
+			"name":     workspace.Name,
+			"personal": workspace.Personal,
+			"owner_id": workspace.OwnerID,
+			"role":     member.Role,
+			"active":   workspace.ID == user.ActiveWorkspaceID,
+		})
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// CreateWorkspace creates a team workspace owned by the authenticated user
+func CreateWorkspace(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	var request struct {
+		Name string `json:"name"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil || strings.TrimSpace(request.Name) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name is required"})
+		return
+	}
+
+	workspace := models.Workspace{Name: strings.TrimSpace(request.Name), OwnerID: user.ID}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&workspace).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.WorkspaceMember{WorkspaceID: workspace.ID, UserID: user.ID, Role: models.WorkspaceRoleOwner}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create workspace"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"id": workspace.ID})
+}
+
+// UpdateWorkspace renames a workspace
+func UpdateWorkspace(c *gin.Context) {
+	workspace, _, ok := findWorkspace(c, models.WorkspaceRoleAdmin)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		Name string `json:"name"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil || strings.TrimSpace(request.Name) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name is required"})
+		return
+	}
+
+	if err := database.DB.Model(&workspace).Update("name", strings.TrimSpace(request.Name)).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update workspace"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// DeleteWorkspace deletes a team workspace with its task groups, tasks and notes
+func DeleteWorkspace(c *gin.Context) {
+	workspace, _, ok := findWorkspace(c, models.WorkspaceRoleOwner)
+	if !ok {
+		return
+	}
+
+	if workspace.Personal {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The personal workspace cannot be deleted"})
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		return database.DeleteWorkspaces(tx, []uint{workspace.ID}, false)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete workspace"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Workspace deleted successfully"})
+}
+
+// SwitchWorkspace makes a workspace the active one of the authenticated user
+func SwitchWorkspace(c *gin.Context) {
+	workspace, _, ok := findWorkspace(c, models.WorkspaceRoleViewer)
+	if !ok {
+		return
+	}
+
+	if err := database.DB.Model(&models.User{}).Where("id = ?", middleware.CurrentUser(c).ID).
+		Update("active_workspace_id", workspace.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to switch workspace"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// GetWorkspaceMembers lists the members of a workspace
+func GetWorkspaceMembers(c *gin.Context) {
+	workspace, _, ok := findWorkspace(c, models.WorkspaceRoleViewer)
+	if !ok {
+		return
+	}
+
+	var members []models.WorkspaceMember
+	database.DB.Where("workspace_id = ?", workspace.ID).Order("created_at").Find(&members)
+
+	response := []gin.H{}
+	for _, member := range members {
+		var user models.User
+		if err := database.DB.First(&user, member.UserID).Error; err != nil {
+			continue
+		}
+		response = append(response, memberResponse(user, member.Role))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// UpdateWorkspaceMember changes the role of a member; the owner's role cannot change
+func UpdateWorkspaceMember(c *gin.Context) {
+	workspace, _, ok := findWorkspace(c, models.WorkspaceRoleAdmin)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		Role string `json:"role"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil || !validWorkspaceRole(request.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Role must be viewer, member or admin"})
+		return
+	}
+
+	result := database.DB.Model(&models.WorkspaceMember{}).
+		Where("workspace_id = ? AND user_id = ? AND role <> ?", workspace.ID, c.Param("user_id"), models.WorkspaceRoleOwner).
+		Update("role", request.Role)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update member"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// RemoveWorkspaceMember removes a member; admins can remove anyone but the owner and members can leave
+func RemoveWorkspaceMember(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	required := models.WorkspaceRoleAdmin
+	if c.Param("user_id") == strconv.FormatUint(uint64(user.ID), 10) {
+		required = models.WorkspaceRoleViewer
+	}
+
+	workspace, _, ok := findWorkspace(c, required)
+	if !ok {
+		return
+	}
+
+	result := database.DB.
+		Where("workspace_id = ? AND user_id = ? AND role <> ?", workspace.ID, c.Param("user_id"), models.WorkspaceRoleOwner).
+		Delete(&models.WorkspaceMember{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove member"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+		return
+	}
+
+	// Former members stay assigned only where a direct share still gives them access
+	memberID, _ := strconv.ParseUint(c.Param("user_id"), 10, 64)
+	var taskGroups []models.TaskGroup
+	database.DB.Where("workspace_id = ?", workspace.ID).Find(&taskGroups)
+	for _, taskGroup := range taskGroups {
+		if err := unassignWithoutAccess(taskGroup, uint(memberID), user.ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unassign member"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// GetWorkspaceInvitations lists the pending invitations of a workspace
+func GetWorkspaceInvitations(c *gin.Context) {
+	workspace, _, ok := findWorkspace(c, models.WorkspaceRoleAdmin)
+	if !ok {
+		return
+	}
+
+	invitations := []models.WorkspaceInvitation{}
+	if err := database.DB.Where("workspace_id = ? AND accepted_at IS NULL AND expires_at > ?", workspace.ID, time.Now()).
+		Order("created_at DESC").Find(&invitations).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve invitations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, invitations)
+}
+
+// CreateWorkspaceInvitation emails an invitation to join a team workspace
+func CreateWorkspaceInvitation(c *gin.Context) {
+	workspace, _, ok := findWorkspace(c, models.WorkspaceRoleAdmin)
+	if !ok {
+		return
+	}
+
+	if workspace.Personal {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The personal workspace cannot be shared"})
+		return
+	}
+
+	var request struct {
+		Email string `json:"email"`
+		Role  string `json:"role"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil || !validWorkspaceRole(request.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Email and a role of viewer, member or admin are required"})
+		return
+	}
+
+	email := strings.ToLower(strings.TrimSpace(request.Email))
+	if _, err := mail.ParseAddress(email); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid email"})
+		return
+	}
+
+	var existing int64
+	database.DB.Model(&models.WorkspaceMember{}).
+		Where("workspace_id = ? AND user_id IN (?)", workspace.ID, database.DB.Model(&models.User{}).Select("id").Where("LOWER(email) = ?", email)).
+		Count(&existing)
+	if existing > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "User is already a member"})
+		return
+	}
+
+	token, err := utils.GenerateToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	// A new invitation replaces the pending one for the same address
+	database.DB.Where("workspace_id = ? AND email = ? AND accepted_at IS NULL", workspace.ID, email).Delete(&models.WorkspaceInvitation{})
+
+	inviter := middleware.CurrentUser(c)
+	invitation := models.WorkspaceInvitation{
+		WorkspaceID: workspace.ID,
+		Email:       email,
+		Role:        request.Role,
+		TokenHash:   utils.HashToken(token),
+		InvitedByID: inviter.ID,
+		ExpiresAt:   time.Now().Add(workspaceInvitationTTL),
+	}
+	if err := database.DB.Create(&invitation).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invitation"})
+		return
+	}
+
+	if err := mailer.SendTemplate(email, mailer.TemplateWorkspaceInvite, map[string]interface{}{
+		"InviterName":   inviter.FullName,
+		"WorkspaceName": workspace.Name,
+		"Role":          invitation.Role,
+		"Token":         token,
+		"ExpiresIn":     fmt.Sprintf("%d days", int(workspaceInvitationTTL.Hours()/24)),
+	}); err != nil {
+		log.Printf("Failed to send workspace invitation to %s: %v", email, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send invitation"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, invitation)
+}
+
+// RevokeWorkspaceInvitation cancels a pending invitation
+func RevokeWorkspaceInvitation(c *gin.Context) {
+	workspace, _, ok := findWorkspace(c, models.WorkspaceRoleAdmin)
+	if !ok {
+		return
+	}
+
+	result := database.DB.Where("id = ? AND workspace_id = ? AND accepted_at IS NULL", c.Param("invitation_id"), workspace.ID).
+		Delete(&models.WorkspaceInvitation{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke invitation"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// AcceptWorkspaceInvitation joins a workspace with an invitation sent to the authenticated user's email
+func AcceptWorkspaceInvitation(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	var request struct {
+		Token string `json:"token"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil || request.Token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Token is required"})
+		return
+	}
+
+	var invitation models.WorkspaceInvitation
+	if err := database.DB.Where("token_hash = ? AND accepted_at IS NULL AND expires_at > ?", utils.HashToken(request.Token), time.Now()).
+		First(&invitation).Error; err != nil || !strings.EqualFold(invitation.Email, user.Email) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired invitation"})
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		// The accepted_at condition makes the invitation single-use
+		result := tx.Model(&invitation).Where("accepted_at IS NULL").Update("accepted_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		var existing int64
+		tx.Model(&models.WorkspaceMember{}).Where("workspace_id = ? AND user_id = ?", invitation.WorkspaceID, user.ID).Count(&existing)
+		if existing > 0 {
+			return nil
+		}
+		return tx.Create(&models.WorkspaceMember{WorkspaceID: invitation.WorkspaceID, UserID: user.ID, Role: invitation.Role}).Error
+	})
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired invitation"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to accept invitation"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"workspace_id": invitation.WorkspaceID})
+}
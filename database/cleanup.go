@@ -0,0 +1,65 @@
+package database
+
+import (
+	"gorm.io/gorm"
+	"material_todo_go/models"
+)
+
+// ownedRows selects the rows of a model to delete, as in tx.Where(query, args...).Delete(model)
+type ownedRows struct {
+	model interface{}
+	query interface{}
+	args  []interface{}
+}
+
+// deleteRows deletes each selection in order. Soft-deletable models are only marked deleted unless permanently is set.
+func deleteRows(tx *gorm.DB, permanently bool, rows []ownedRows) error {
+	for _, r := range rows {
+		db := tx
+		if permanently {
+			db = tx.Unscoped()
+		}
+		if err := db.Where(r.query, r.args...).Delete(r.model).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteTaskGroups deletes task groups with their tasks, workflows, members, activity and everything
+// attached to their tasks. groupIDs is a slice of IDs or a subquery selecting them.
+func DeleteTaskGroups(tx *gorm.DB, groupIDs interface{}, permanently bool) error {
+	taskIDs := tx.Model(&models.Task{}).Unscoped().Select("id").Where("task_group_id IN (?)", groupIDs)
+
+	return deleteRows(tx, permanently, []ownedRows{
+		{&models.TaskAssignee{}, "task_id IN (?)", []interface{}{taskIDs}},
+		{&models.TaskHistory{}, "task_id IN (?)", []interface{}{taskIDs}},
+		{&models.TaskComment{}, "task_id IN (?)", []interface{}{taskIDs}},
+		{&models.Notification{}, "(entity_type = ? AND entity_id IN (?)) OR (entity_type = ? AND entity_id IN (?))",
+			[]interface{}{models.NotificationEntityTask, taskIDs, models.NotificationEntityTaskGroup, groupIDs}},
+		{&models.Task{}, "task_group_id IN (?)", []interface{}{groupIDs}},
+		{&models.TaskGroupActivity{}, "task_group_id IN (?)", []interface{}{groupIDs}},
+		{&models.TaskGroupTransition{}, "task_group_id IN (?)", []interface{}{groupIDs}},
+		{&models.TaskGroupStatus{}, "task_group_id IN (?)", []interface{}{groupIDs}},
+		{&models.TaskGroupMember{}, "task_group_id IN (?)", []interface{}{groupIDs}},
+		{&models.TaskGroup{}, "id IN (?)", []interface{}{groupIDs}},
+	})
+}
+
+// DeleteWorkspaces deletes workspaces with their task groups, notes, members and invitations.
+// workspaceIDs is a slice of IDs or a subquery selecting them.
+func DeleteWorkspaces(tx *gorm.DB, workspaceIDs interface{}, permanently bool) error {
+	groupIDs := tx.Model(&models.TaskGroup{}).Unscoped().Select("id").Where("workspace_id IN (?)", workspaceIDs)
+	if err := DeleteTaskGroups(tx, groupIDs, permanently); err != nil {
+		return err
+	}
+
+	noteIDs := tx.Model(&models.Note{}).Unscoped().Select("id").Where("workspace_id IN (?)", workspaceIDs)
+	return deleteRows(tx, permanently, []ownedRows{
+		{&models.Notification{}, "entity_type = ? AND entity_id IN (?)", []interface{}{models.NotificationEntityNote, noteIDs}},
+		{&models.Note{}, "workspace_id IN (?)", []interface{}{workspaceIDs}},
+		{&models.WorkspaceInvitation{}, "workspace_id IN (?)", []interface{}{workspaceIDs}},
+		{&models.WorkspaceMember{}, "workspace_id IN (?)", []interface{}{workspaceIDs}},
+		{&models.Workspace{}, "id IN (?)", []interface{}{workspaceIDs}},
+	})
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"log"
 	"material_todo_go/models"
+	"material_todo_go/utils"
+	"strings"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -32,8 +34,87 @@ func ConnectDB() {
 	}
 
 	fmt.Println("✅ Successfully connected to PostgreSQL!")
+
+	// Accounts created before email verification existed are treated as verified
+	backfillVerified := DB.Migrator().HasTable(&models.User{}) && !DB.Migrator().HasColumn(&models.User{}, "EmailVerified")
 	DB.AutoMigrate(&models.User{})
+	if backfillVerified {
+		DB.Model(&models.User{}).Where("1 = 1").Update("email_verified", true)
+	}
+
 	DB.AutoMigrate(&models.Note{})
 	DB.AutoMigrate(&models.TaskGroup{})
+	// Tasks used to have no owner, they inherit it from their task group
+	backfillTaskOwners := DB.Migrator().HasTable(&models.Task{}) && !DB.Migrator().HasColumn(&models.Task{}, "UserID")
 	DB.AutoMigrate(&models.Task{})
+	if backfillTaskOwners {
+		DB.Exec("UPDATE tasks SET user_id = task_groups.user_id FROM task_groups WHERE tasks.task_group_id = task_groups.id")
+	}
+	DB.AutoMigrate(&models.TaskGroupMember{})
+	DB.AutoMigrate(&models.TaskAssignee{})
+	DB.AutoMigrate(&models.TaskHistory{})
+	DB.AutoMigrate(&models.TaskComment{})
+	DB.AutoMigrate(&models.TaskGroupActivity{})
+	DB.AutoMigrate(&models.TaskGroupStatus{})
+	DB.AutoMigrate(&models.TaskGroupTransition{})
+	backfillWorkflows()
+	DB.AutoMigrate(&models.Notification{})
+	DB.AutoMigrate(&models.NotificationPreference{})
+	DB.AutoMigrate(&models.PasswordReset{})
+	DB.AutoMigrate(&models.EmailVerification{})
+	DB.AutoMigrate(&models.Session{})
+	// Refresh tokens moved from token families to sessions
+	if DB.Migrator().HasColumn(&models.RefreshToken{}, "family_id") {
+		DB.Migrator().DropColumn(&models.RefreshToken{}, "family_id")
+		DB.Where("1 = 1").Delete(&models.RefreshToken{})
+	}
+	DB.AutoMigrate(&models.RefreshToken{})
+	DB.AutoMigrate(&models.RevokedToken{})
+	DB.AutoMigrate(&models.SigningKey{})
+	DB.AutoMigrate(&models.LoginAttempt{})
+	DB.AutoMigrate(&models.AccountUnlock{})
+	DB.AutoMigrate(&models.RecoveryCode{})
+	DB.AutoMigrate(&models.OAuthIdentity{})
+	DB.AutoMigrate(&models.OAuthLogin{})
+	DB.AutoMigrate(&models.PersonalAccessToken{})
+	DB.AutoMigrate(&models.EmailChange{})
+	DB.AutoMigrate(&models.AuditEvent{})
+	DB.AutoMigrate(&models.Workspace{})
+	DB.AutoMigrate(&models.WorkspaceMember{})
+	DB.AutoMigrate(&models.WorkspaceInvitation{})
+	backfillWorkspaces()
+	protectAuditEvents()
+
+	// Bootstrap administrators from configuration, once they have proven they own the address
+	if len(config.AdminEmails) > 0 {
+		adminEmails := make([]string, len(config.AdminEmails))
+		for i, email := range config.AdminEmails {
+			adminEmails[i] = strings.ToLower(email)
+		}
+		DB.Model(&models.User{}).Where("LOWER(email) IN ? AND email_verified = true", adminEmails).Update("role", models.RoleAdmin)
+	}
+
+	utils.SetRevocationStore(RevocationStore{})
+}
+
+// protectAuditEvents makes the audit log append-only at the database level
+func protectAuditEvents() {
+	statements := []string{
+		`CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+		BEGIN
+			RAISE EXCEPTION 'audit_events is append-only';
+		END;
+		$$ LANGUAGE plpgsql`,
+		`DROP TRIGGER IF EXISTS audit_events_no_change ON audit_events`,
+		`CREATE TRIGGER audit_events_no_change BEFORE UPDATE OR DELETE ON audit_events
+		FOR EACH ROW EXECUTE PROCEDURE audit_events_append_only()`,
+		`DROP TRIGGER IF EXISTS audit_events_no_truncate ON audit_events`,
+		`CREATE TRIGGER audit_events_no_truncate BEFORE TRUNCATE ON audit_events
+		FOR EACH STATEMENT EXECUTE PROCEDURE audit_events_append_only()`,
+	}
+	for _, statement := range statements {
+		if err := DB.Exec(statement).Error; err != nil {
+			log.Fatalf("❌ Failed to protect audit log: %v", err)
+		}
+	}
 }
@@ -0,0 +1,58 @@
+package database
+
+import (
+	"material_todo_go/models"
+	"material_todo_go/utils"
+	"time"
+)
+
+// KeyStore persists JWT signing keys in the signing_keys table.
+type KeyStore struct{}
+
+func (KeyStore) LoadKeys() ([]*utils.SigningKey, error) {
+	var records []models.SigningKey
+	if err := DB.Find(&records).Error; err != nil {
+		return nil, err
+	}
+
+	keys := make([]*utils.SigningKey, 0, len(records))
+	for _, record := range records {
+		private, err := utils.DecodePrivateKey(record.PrivateKey)
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, &utils.SigningKey{
+			ID:        record.ID,
+			Algorithm: record.Algorithm,
+			Private:   private,
+			CreatedAt: record.CreatedAt,
+			RetiredAt: record.RetiredAt,
+		})
+	}
+	return keys, nil
+}
+
+func (KeyStore) SaveKey(key *utils.SigningKey) error {
+	encoded, err := key.EncodePrivateKey()
+	if err != nil {
+		return err
+	}
+
+	return DB.Create(&models.SigningKey{
+		ID:         key.ID,
+		Algorithm:  key.Algorithm,
+		PrivateKey: encoded,
+		CreatedAt:  key.CreatedAt,
+	}).Error
+}
+
+func (KeyStore) RetireOlderKeys(algorithm string, at time.Time) error {
+	newest := DB.Model(&models.SigningKey{}).Select("id").
+		Where("algorithm = ? AND retired_at IS NULL", algorithm).Order("created_at DESC, id DESC").Limit(1)
+	return DB.Model(&models.SigningKey{}).Where("retired_at IS NULL AND id NOT IN (?)", newest).Update("retired_at", at).Error
+}
+
+func (KeyStore) DeleteKey(id string) error {
+	return DB.Delete(&models.SigningKey{}, "id = ?", id).Error
+}
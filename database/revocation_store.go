@@ -0,0 +1,36 @@
+package database
+
+import (
+	"material_todo_go/models"
+	"material_todo_go/utils"
+	"time"
+)
+
+// RevocationStore checks tokens against revoked_tokens, their session and the user's TokensValidAfter.
+type RevocationStore struct{}
+
+func (RevocationStore) IsRevoked(claims *utils.Claims) bool {
+	var count int64
+	if err := DB.Model(&models.RevokedToken{}).Where("jti = ?", claims.ID).Count(&count).Error; err != nil || count > 0 {
+		return true
+	}
+
+	if claims.SessionID != 0 {
+		var session models.Session
+		if err := DB.Select("revoked_at").First(&session, claims.SessionID).Error; err != nil || session.RevokedAt != nil {
+			return true
+		}
+	}
+
+	var user models.User
+	if err := DB.Select("tokens_valid_after").Where("email = ?", claims.Email).First(&user).Error; err != nil {
+		return true
+	}
+
+	// iat has second precision, so tokens issued within the revocation second are rejected as well
+	if user.TokensValidAfter != nil && !claims.IssuedAt.Time.After(user.TokensValidAfter.Truncate(time.Second)) {
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,75 @@
+package database
+
+import (
+	"gorm.io/gorm"
+	"log"
+	"material_todo_go/models"
+)
+
+// TaskStatusInCategory is a condition on tasks matching those whose status belongs to the given category
+const TaskStatusInCategory = `EXISTS (SELECT 1 FROM task_group_statuses
+	WHERE task_group_statuses.task_group_id = tasks.task_group_id
+	AND task_group_statuses.name = tasks.status
+	AND task_group_statuses.category = ?)`
+
+// defaultStatuses is the workflow of new task groups, matching the statuses used before workflows existed
+var defaultStatuses = []models.TaskGroupStatus{
+	{Name: "TODO", Category: models.StatusCategoryTodo},
+	{Name: "IN PROGRESS", Category: models.StatusCategoryInProgress},
+	{Name: "COMPLETED", Category: models.StatusCategoryDone},
+}
+
+// CreateDefaultStatuses gives a task group the default workflow
+func CreateDefaultStatuses(tx *gorm.DB, taskGroupID uint) error {
+	for position, status := range defaultStatuses {
+		status.TaskGroupID = taskGroupID
+		status.Position = position
+		if err := tx.Create(&status).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backfillWorkflows gives task groups created before workflows existed the default workflow,
+// plus a "todo" status for every other status their tasks already use
+func backfillWorkflows() {
+	var groupIDs []uint
+	DB.Model(&models.TaskGroup{}).Unscoped().
+		Where("id NOT IN (?)", DB.Model(&models.TaskGroupStatus{}).Select("task_group_id")).
+		Pluck("id", &groupIDs)
+
+	for _, groupID := range groupIDs {
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			if err := CreateDefaultStatuses(tx, groupID); err != nil {
+				return err
+			}
+
+			// Tasks without a status start at the beginning of the workflow
+			if err := tx.Model(&models.Task{}).Unscoped().Where("task_group_id = ? AND status = ''", groupID).
+				Update("status", defaultStatuses[0].Name).Error; err != nil {
+				return err
+			}
+
+			var extra []string
+			tx.Model(&models.Task{}).Unscoped().Distinct("status").
+				Where("task_group_id = ? AND status <> '' AND status NOT IN ?", groupID, []string{"TODO", "IN PROGRESS", "COMPLETED"}).
+				Pluck("status", &extra)
+			for i, name := range extra {
+				status := models.TaskGroupStatus{
+					TaskGroupID: groupID,
+					Name:        name,
+					Position:    len(defaultStatuses) + i,
+					Category:    models.StatusCategoryTodo,
+				}
+				if err := tx.Create(&status).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			log.Printf("Failed to create the workflow of task group %d: %v", groupID, err)
+		}
+	}
+}
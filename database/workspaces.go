@@ -0,0 +1,53 @@
+package database
+
+import (
+	"gorm.io/gorm"
+	"log"
+	"material_todo_go/models"
+)
+
+// CreatePersonalWorkspace creates the personal workspace of a new user and makes it active
+func CreatePersonalWorkspace(user *models.User) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		return createPersonalWorkspace(tx, user)
+	})
+}
+
+func createPersonalWorkspace(tx *gorm.DB, user *models.User) error {
+	workspace := models.Workspace{Name: "Personal", OwnerID: user.ID, Personal: true}
+	if err := tx.Create(&workspace).Error; err != nil {
+		return err
+	}
+
+	member := models.WorkspaceMember{WorkspaceID: workspace.ID, UserID: user.ID, Role: models.WorkspaceRoleOwner}
+	if err := tx.Create(&member).Error; err != nil {
+		return err
+	}
+
+	user.ActiveWorkspaceID = workspace.ID
+	return tx.Model(user).Update("active_workspace_id", workspace.ID).Error
+}
+
+// backfillWorkspaces gives users created before workspaces existed a personal workspace
+// and moves their task groups and notes into it
+func backfillWorkspaces() {
+	var users []models.User
+	DB.Where("id NOT IN (?)", DB.Model(&models.Workspace{}).Select("owner_id").Where("personal = ?", true)).Find(&users)
+
+	for _, user := range users {
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			if err := createPersonalWorkspace(tx, &user); err != nil {
+				return err
+			}
+			if err := tx.Model(&models.TaskGroup{}).Unscoped().Where("user_id = ? AND workspace_id = 0", user.ID).
+				Update("workspace_id", user.ActiveWorkspaceID).Error; err != nil {
+				return err
+			}
+			return tx.Model(&models.Note{}).Unscoped().Where("user_id = ? AND workspace_id = 0", user.ID).
+				Update("workspace_id", user.ActiveWorkspaceID).Error
+		})
+		if err != nil {
+			log.Printf("Failed to create personal workspace for user %d: %v", user.ID, err)
+		}
+	}
+}
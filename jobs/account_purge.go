@@ -0,0 +1,106 @@
+package jobs
+
+import (
+	"gorm.io/gorm"
+	"log"
+	"material_todo_go/database"
+	"material_todo_go/models"
+	"os"
+	"time"
+)
+
+// defaultAvatar is shared by every account and must never be removed
+const defaultAvatar = "uploads/default_avatar.png"
+
+// StartAccountPurge permanently deletes accounts whose deletion grace period has ended, once at start and then hourly
+func StartAccountPurge() {
+	go func() {
+		for {
+			PurgeDeletedAccounts()
+			time.Sleep(time.Hour)
+		}
+	}()
+}
+
+// PurgeDeletedAccounts deletes every account scheduled for deletion before now
+func PurgeDeletedAccounts() {
+	var users []models.User
+	if err := database.DB.Where("deletion_scheduled_at IS NOT NULL AND deletion_scheduled_at <= ?", time.Now()).Find(&users).Error; err != nil {
+		log.Printf("Failed to load accounts scheduled for deletion: %v", err)
+		return
+	}
+
+	for _, user := range users {
+		if err := PurgeUser(user); err != nil {
+			log.Printf("Failed to delete account %d: %v", user.ID, err)
+			continue
+		}
+		log.Printf("Deleted account %d", user.ID)
+	}
+}
+
+// PurgeUser permanently removes a user together with everything they own and their uploaded avatar
+func PurgeUser(user models.User) error {
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		// Workspaces the user owns go away with them, including content other members added
+		workspaceIDs := tx.Model(&models.Workspace{}).Select("id").Where("owner_id = ?", user.ID)
+		groupIDs := tx.Model(&models.TaskGroup{}).Unscoped().Select("id").Where("user_id = ?", user.ID)
+		noteIDs := tx.Model(&models.Note{}).Unscoped().Select("id").Where("user_id = ?", user.ID)
+
+		if err := database.DeleteTaskGroups(tx, groupIDs, true); err != nil {
+			return err
+		}
+		if err := database.DeleteWorkspaces(tx, workspaceIDs, true); err != nil {
+			return err
+		}
+
+		owned := []struct {
+			model interface{}
+			query interface{}
+			args  []interface{}
+		}{
+			{&models.TaskAssignee{}, "user_id = ?", []interface{}{user.ID}},
+			{&models.TaskComment{}, "author_id = ?", []interface{}{user.ID}},
+			{&models.Notification{}, "user_id = ? OR (entity_type = ? AND entity_id IN (?))", []interface{}{user.ID, models.NotificationEntityNote, noteIDs}},
+			{&models.NotificationPreference{}, "user_id = ?", []interface{}{user.ID}},
+			{&models.TaskGroupMember{}, "user_id = ?", []interface{}{user.ID}},
+			{&models.Note{}, "user_id = ?", []interface{}{user.ID}},
+			{&models.WorkspaceMember{}, "user_id = ?", []interface{}{user.ID}},
+			{&models.PasswordReset{}, "user_id = ?", []interface{}{user.ID}},
+			{&models.EmailVerification{}, "user_id = ?", []interface{}{user.ID}},
+			{&models.RefreshToken{}, "user_id = ?", []interface{}{user.ID}},
+			{&models.RevokedToken{}, "user_id = ?", []interface{}{user.ID}},
+			{&models.Session{}, "user_id = ?", []interface{}{user.ID}},
+			{&models.AccountUnlock{}, "user_id = ?", []interface{}{user.ID}},
+			{&models.RecoveryCode{}, "user_id = ?", []interface{}{user.ID}},
+			{&models.OAuthIdentity{}, "user_id = ?", []interface{}{user.ID}},
+			{&models.OAuthLogin{}, "user_id = ?", []interface{}{user.ID}},
+			{&models.PersonalAccessToken{}, "user_id = ?", []interface{}{user.ID}},
+			{&models.EmailChange{}, "user_id = ?", []interface{}{user.ID}},
+		}
+
+		// Audit events are append-only and outlive the account
+		for _, o := range owned {
+			if err := tx.Unscoped().Where(o.query, o.args...).Delete(o.model).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Unscoped().Delete(&models.User{}, user.ID).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	// Uploads are stored by file name, so keep the file if another account points at it
+	if user.Image != "" && user.Image != defaultAvatar {
+		var sharedBy int64
+		database.DB.Model(&models.User{}).Where("image = ?", user.Image).Count(&sharedBy)
+		if sharedBy == 0 {
+			if err := os.Remove(user.Image); err != nil && !os.IsNotExist(err) {
+				log.Printf("Failed to remove avatar %s: %v", user.Image, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package jobs
+
+import (
+	"fmt"
+	"log"
+	"material_todo_go/database"
+	"material_todo_go/models"
+	"material_todo_go/notifications"
+	"time"
+)
+
+// dueSoonWindow is how long before its finish date a task counts as due soon
+const dueSoonWindow = 24 * time.Hour
+
+// StartDueSoonNotifications notifies users about unfinished tasks due within a day, once at start and then every 15 minutes
+func StartDueSoonNotifications() {
+	go func() {
+		for {
+			NotifyDueSoonTasks()
+			time.Sleep(15 * time.Minute)
+		}
+	}()
+}
+
+// NotifyDueSoonTasks notifies the assignees, or the owner of unassigned tasks, once per finish date
+func NotifyDueSoonTasks() {
+	var tasks []models.Task
+	if err := database.DB.Preload("Assignees").
+		Where("due_soon_notified_at IS NULL AND finish_date > ? AND finish_date <= ? AND NOT "+database.TaskStatusInCategory,
+			time.Now(), time.Now().Add(dueSoonWindow), models.StatusCategoryDone).
+		Find(&tasks).Error; err != nil {
+		log.Printf("Failed to load tasks due soon: %v", err)
+		return
+	}
+
+	for _, task := range tasks {
+		userIDs := []uint{task.UserID}
+		if len(task.Assignees) > 0 {
+			userIDs = userIDs[:0]
+			for _, assignee := range task.Assignees {
+				userIDs = append(userIDs, assignee.UserID)
+			}
+		}
+
+		if err := database.DB.Model(&task).Update("due_soon_notified_at", time.Now()).Error; err != nil {
+			log.Printf("Failed to mark task %d as notified: %v", task.ID, err)
+			continue
+		}
+
+		for _, userID := range userIDs {
+			notifications.Send(models.Notification{
+				UserID:     userID,
+				Type:       models.NotificationDueSoon,
+				Message:    fmt.Sprintf("Task %q is due %s", task.Title, task.FinishDate.Format("Jan 2 15:04")),
+				EntityType: notifications.EntityTask,
+				EntityID:   task.ID,
+			})
+		}
+	}
+}
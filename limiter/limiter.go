@@ -0,0 +1,125 @@
+package limiter
+
+import (
+	"fmt"
+	"material_todo_go/config"
+	"math"
+	"time"
+)
+
+// Store keeps failure counters so they can be shared by several backend instances.
+type Store interface {
+	// Get returns the failures recorded for key within window and the time of the last one
+	Get(key string, window time.Duration) (int, time.Time, error)
+	// Increment records a failure at now and returns the new count, restarting it if the last failure is older than window
+	Increment(key string, now time.Time, window time.Duration) (int, error)
+	Reset(key string) error
+}
+
+// Policy describes how failures turn into waiting time.
+type Policy struct {
+	FreeAttempts    int           // failures allowed before any delay
+	BaseDelay       time.Duration // first delay, doubled on every further failure
+	MaxDelay        time.Duration
+	LockoutAfter    int // failures that lock the key for LockoutDuration, 0 disables
+	LockoutDuration time.Duration
+	Window          time.Duration // failures older than this are forgotten
+}
+
+// Delay returns how long to wait after the given number of failures
+func (p Policy) Delay(failures int) time.Duration {
+	if p.LockoutAfter > 0 && failures >= p.LockoutAfter {
+		return p.LockoutDuration
+	}
+	if failures <= p.FreeAttempts {
+		return 0
+	}
+
+	delay := time.Duration(float64(p.BaseDelay) * math.Pow(2, float64(failures-p.FreeAttempts-1)))
+	if delay > p.MaxDelay || delay <= 0 {
+		delay = p.MaxDelay
+	}
+	return delay
+}
+
+// Limiter applies a Policy to counters kept in a Store.
+type Limiter struct {
+	Store  Store
+	Policy Policy
+}
+
+// Check returns how long the key still has to wait before its next attempt
+func (l *Limiter) Check(key string) (time.Duration, error) {
+	failures, last, err := l.Store.Get(key, l.Policy.Window)
+	if err != nil || failures == 0 {
+		return 0, err
+	}
+
+	if wait := time.Until(last.Add(l.Policy.Delay(failures))); wait > 0 {
+		return wait, nil
+	}
+	return 0, nil
+}
+
+// Fail records a failure and returns the new failure count and the resulting wait
+func (l *Limiter) Fail(key string) (int, time.Duration, error) {
+	failures, err := l.Store.Increment(key, time.Now(), l.Policy.Window)
+	if err != nil {
+		return 0, 0, err
+	}
+	return failures, l.Policy.Delay(failures), nil
+}
+
+// Reset forgets all failures of the key
+func (l *Limiter) Reset(key string) error {
+	return l.Store.Reset(key)
+}
+
+// Limiters used by the auth endpoints, created by Setup
+var (
+	Accounts   *Limiter // failed logins per account
+	IPs        *Limiter // failed logins per client IP
+	ResetCodes *Limiter // reset code requests per account
+)
+
+// Setup creates the limiters on the store selected by config.LoginAttemptStore
+func Setup() error {
+	var store Store
+	switch config.LoginAttemptStore {
+	case "", "memory":
+		store = NewMemoryStore()
+	case "postgres":
+		store = PostgresStore{}
+	default:
+		return fmt.Errorf("unknown login attempt store %q", config.LoginAttemptStore)
+	}
+
+	Accounts = &Limiter{Store: store, Policy: Policy{
+		FreeAttempts:    3,
+		BaseDelay:       time.Second,
+		MaxDelay:        5 * time.Minute,
+		LockoutAfter:    config.LoginLockoutThreshold,
+		LockoutDuration: config.LoginLockoutDuration,
+		Window:          24 * time.Hour,
+	}}
+	IPs = &Limiter{Store: store, Policy: Policy{
+		FreeAttempts: 20,
+		BaseDelay:    time.Second,
+		MaxDelay:     15 * time.Minute,
+		Window:       time.Hour,
+	}}
+	ResetCodes = &Limiter{Store: store, Policy: Policy{
+		FreeAttempts: 3,
+		BaseDelay:    time.Minute,
+		MaxDelay:     time.Hour,
+		Window:       24 * time.Hour,
+	}}
+
+	fmt.Printf("✅ Login attempt limiter configured (%T)\n", store)
+	return nil
+}
+
+// RetryAfter formats a wait for the Retry-After header, in whole seconds rounded up
+func RetryAfter(wait time.Duration) string {
+	return fmt.Sprintf("%d", int(math.Ceil(wait.Seconds())))
+}
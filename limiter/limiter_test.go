@@ -0,0 +1,154 @@
+package limiter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPolicyDelay(t *testing.T) {
+	policy := Policy{
+		FreeAttempts:    3,
+		BaseDelay:       time.Second,
+		MaxDelay:        30 * time.Second,
+		LockoutAfter:    10,
+		LockoutDuration: 30 * time.Minute,
+	}
+
+	tests := []struct {
+		name     string
+		failures int
+		want     time.Duration
+	}{
+		{"no failures", 0, 0},
+		{"within free attempts", 3, 0},
+		{"first delayed failure", 4, time.Second},
+		{"doubles", 5, 2 * time.Second},
+		{"doubles again", 7, 8 * time.Second},
+		{"capped at max delay", 9, 30 * time.Second},
+		{"lockout threshold", 10, 30 * time.Minute},
+		{"beyond lockout threshold", 50, 30 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.Delay(tt.failures); got != tt.want {
+				t.Errorf("Delay(%d) = %s, want %s", tt.failures, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicyDelayWithoutLockout(t *testing.T) {
+	policy := Policy{FreeAttempts: 0, BaseDelay: time.Second, MaxDelay: time.Hour}
+
+	// Large counts overflow the exponent and must still be capped
+	if got := policy.Delay(1000); got != time.Hour {
+		t.Errorf("Delay(1000) = %s, want %s", got, time.Hour)
+	}
+}
+
+func TestLimiterFailCheckReset(t *testing.T) {
+	limiter := &Limiter{Store: NewMemoryStore(), Policy: Policy{
+		FreeAttempts:    1,
+		BaseDelay:       time.Minute,
+		MaxDelay:        time.Hour,
+		LockoutAfter:    3,
+		LockoutDuration: 2 * time.Hour,
+		Window:          24 * time.Hour,
+	}}
+
+	steps := []struct {
+		wantFailures int
+		wantWait     time.Duration // upper bound of the wait reported by Check
+		wantBlocked  bool
+	}{
+		{1, 0, false},
+		{2, time.Minute, true},
+		{3, 2 * time.Hour, true},
+	}
+	for _, step := range steps {
+		failures, wait, err := limiter.Fail("account")
+		if err != nil {
+			t.Fatalf("Fail: %v", err)
+		}
+		if failures != step.wantFailures || wait != step.wantWait {
+			t.Errorf("Fail = (%d, %s), want (%d, %s)", failures, wait, step.wantFailures, step.wantWait)
+		}
+
+		remaining, err := limiter.Check("account")
+		if err != nil {
+			t.Fatalf("Check: %v", err)
+		}
+		if (remaining > 0) != step.wantBlocked || remaining > step.wantWait {
+			t.Errorf("after %d failures Check = %s, want blocked=%t and at most %s", failures, remaining, step.wantBlocked, step.wantWait)
+		}
+	}
+
+	if remaining, _ := limiter.Check("other"); remaining != 0 {
+		t.Errorf("unrelated key waits %s", remaining)
+	}
+
+	if err := limiter.Reset("account"); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+	if remaining, _ := limiter.Check("account"); remaining != 0 {
+		t.Errorf("Check after Reset = %s, want 0", remaining)
+	}
+}
+
+func TestMemoryStoreWindow(t *testing.T) {
+	store := NewMemoryStore()
+	start := time.Now().Add(-4 * time.Hour)
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want int
+	}{
+		{"first failure", start, 1},
+		{"within window", start.Add(30 * time.Minute), 2},
+		{"window elapsed since last failure restarts", start.Add(30*time.Minute + 2*time.Hour), 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := store.Increment("key", tt.at, time.Hour)
+			if err != nil {
+				t.Fatalf("Increment: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Increment = %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	if failures, _, _ := store.Get("key", 2*time.Hour); failures != 1 {
+		t.Errorf("Get = %d failures, want 1", failures)
+	}
+	if failures, _, _ := store.Get("key", time.Nanosecond); failures != 0 {
+		t.Errorf("Get with an elapsed window = %d failures, want 0", failures)
+	}
+}
+
+func TestMemoryStorePrunesStaleEntries(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Now()
+
+	store.Increment("stale", now.Add(-memoryEntryTTL-time.Hour), memoryEntryTTL)
+	store.Increment("fresh", now.Add(-time.Hour), memoryEntryTTL)
+	if _, ok := store.entries["stale"]; !ok {
+		t.Fatal("stale entry was pruned before it expired")
+	}
+
+	// Within the prune interval the map is left alone
+	store.Increment("other", store.lastPruned.Add(memoryPruneInterval/2), memoryEntryTTL)
+	if _, ok := store.entries["stale"]; !ok {
+		t.Error("entries were pruned before the prune interval elapsed")
+	}
+
+	store.Increment("other", now.Add(memoryPruneInterval), memoryEntryTTL)
+	if _, ok := store.entries["stale"]; ok {
+		t.Error("stale entry survived pruning")
+	}
+	if _, ok := store.entries["fresh"]; !ok {
+		t.Error("fresh entry was pruned")
+	}
+}
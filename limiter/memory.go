@@ -0,0 +1,71 @@
+package limiter
+
+import (
+	"sync"
+	"time"
+)
+
+type memoryEntry struct {
+	failures int
+	last     time.Time
+}
+
+// MemoryStore keeps counters in process memory. It only protects a single backend instance.
+type MemoryStore struct {
+	mu         sync.Mutex
+	entries    map[string]*memoryEntry
+	lastPruned time.Time
+}
+
+// Entries untouched for memoryEntryTTL are dropped, at most once per memoryPruneInterval
+const (
+	memoryEntryTTL      = 24 * time.Hour
+	memoryPruneInterval = time.Minute
+)
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: map[string]*memoryEntry{}}
+}
+
+func (s *MemoryStore) Get(key string, window time.Duration) (int, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[key]
+	if !ok || time.Since(entry.last) > window {
+		return 0, time.Time{}, nil
+	}
+	return entry.failures, entry.last, nil
+}
+
+func (s *MemoryStore) Increment(key string, now time.Time, window time.Duration) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Drop stale entries so the map does not grow forever, without walking it on every failure
+	if now.Sub(s.lastPruned) >= memoryPruneInterval {
+		for k, entry := range s.entries {
+			if now.Sub(entry.last) > memoryEntryTTL {
+				delete(s.entries, k)
+			}
+		}
+		s.lastPruned = now
+	}
+
+	entry, ok := s.entries[key]
+	if !ok || now.Sub(entry.last) > window {
+		entry = &memoryEntry{}
+		s.entries[key] = entry
+	}
+	entry.failures++
+	entry.last = now
+	return entry.failures, nil
+}
+
+func (s *MemoryStore) Reset(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+	return nil
+}
@@ -0,0 +1,34 @@
+package limiter
+
+import (
+	"material_todo_go/database"
+	"material_todo_go/models"
+	"time"
+)
+
+// PostgresStore keeps counters in the login_attempts table so all instances share them.
+type PostgresStore struct{}
+
+func (PostgresStore) Get(key string, window time.Duration) (int, time.Time, error) {
+	var attempt models.LoginAttempt
+	result := database.DB.Where("key = ? AND last_failure_at > ?", key, time.Now().Add(-window)).Limit(1).Find(&attempt)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return 0, time.Time{}, result.Error
+	}
+	return attempt.Failures, attempt.LastFailureAt, nil
+}
+
+func (PostgresStore) Increment(key string, now time.Time, window time.Duration) (int, error) {
+	var failures int
+	err := database.DB.Raw(`
+		INSERT INTO login_attempts (key, failures, last_failure_at) VALUES (?, 1, ?)
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE WHEN login_attempts.last_failure_at < ? THEN 1 ELSE login_attempts.failures + 1 END,
+			last_failure_at = EXCLUDED.last_failure_at
+		RETURNING failures`, key, now, now.Add(-window)).Scan(&failures).Error
+	return failures, err
+}
+
+func (PostgresStore) Reset(key string) error {
+	return database.DB.Where("key = ?", key).Delete(&models.LoginAttempt{}).Error
+}
@@ -0,0 +1,64 @@
+package mailer
+
+import (
+	"fmt"
+	"material_todo_go/config"
+)
+
+// Message is a single outbound email with a plain text and an HTML body.
+type Message struct {
+	To      []string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Mailer delivers outbound messages.
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// Default is the mailer used by the application, configured by Setup.
+var Default Mailer
+
+// Setup creates the default mailer from the loaded configuration
+func Setup() error {
+	switch config.MailDriver {
+	case "smtp":
+		if config.SMTPHost == "" {
+			return fmt.Errorf("SMTP_HOST is required for the smtp mail driver")
+		}
+		Default = &SMTPMailer{
+			Host:     config.SMTPHost,
+			Port:     config.SMTPPort,
+			Username: config.SMTPUsername,
+			Password: config.SMTPPassword,
+			From:     config.MailFrom,
+		}
+	case "", "outbox":
+		Default = &OutboxMailer{
+			Dir:  config.MailOutboxDir,
+			From: config.MailFrom,
+		}
+	default:
+		return fmt.Errorf("unknown mail driver %q", config.MailDriver)
+	}
+
+	fmt.Printf("✅ Mailer configured (%T)\n", Default)
+	return nil
+}
+
+// SendTemplate renders the named template with data and sends it to a single recipient
+func SendTemplate(to string, name string, data map[string]interface{}) error {
+	if Default == nil {
+		return fmt.Errorf("mailer is not configured")
+	}
+
+	msg, err := Render(name, data)
+	if err != nil {
+		return err
+	}
+	msg.To = []string{to}
+
+	return Default.Send(msg)
+}
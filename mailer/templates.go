@@ -0,0 +1,105 @@
+package mailer
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"material_todo_go/config"
+	texttemplate "text/template"
+)
+
+// Template names accepted by Render and SendTemplate
+const (
+	TemplateResetCode          = "reset_code"
+	TemplateVerifyEmail        = "verify_email"
+	TemplateAccountEvent       = "account_event"
+	TemplateUnlockAccount      = "unlock_account"
+	TemplateConfirmEmailChange = "confirm_email_change"
+	TemplateWorkspaceInvite    = "workspace_invite"
+)
+
+type emailTemplate struct {
+	subject *texttemplate.Template
+	text    *texttemplate.Template
+	html    *htmltemplate.Template
+}
+
+const htmlLayout = `<!DOCTYPE html>
+<html>
+<body style="font-family: Arial, sans-serif; color: #222222;">
+<h2>{{.AppName}}</h2>
+{{template "content" .}}
+<p style="color: #888888; font-size: 12px;">If you did not expect this email, you can safely ignore it.</p>
+</body>
+</html>`
+
+var templates = map[string]emailTemplate{
+	TemplateResetCode: newTemplate(
+		"Your password reset code",
+		"Hello {{.FullName}},\n\nUse this code to reset your password: {{.Code}}\n\nThe code expires in {{.ExpiresIn}}.\n",
+		`<p>Hello {{.FullName}},</p><p>Use this code to reset your password:</p><p style="font-size: 24px; letter-spacing: 4px;"><b>{{.Code}}</b></p><p>The code expires in {{.ExpiresIn}}.</p>`,
+	),
+	TemplateVerifyEmail: newTemplate(
+		"Verify your email address",
+		"Hello {{.FullName}},\n\nUse this token to verify your email address: {{.Token}}\n\nThe token expires in {{.ExpiresIn}}.\n",
+		`<p>Hello {{.FullName}},</p><p>Use this token to verify your email address:</p><p><b>{{.Token}}</b></p><p>The token expires in {{.ExpiresIn}}.</p>`,
+	),
+	TemplateConfirmEmailChange: newTemplate(
+		"Confirm your new email address",
+		"Hello {{.FullName}},\n\nUse this token to confirm this address as the new email of your account: {{.Token}}\n\nThe token expires in {{.ExpiresIn}}.\n",
+		`<p>Hello {{.FullName}},</p><p>Use this token to confirm this address as the new email of your account:</p><p><b>{{.Token}}</b></p><p>The token expires in {{.ExpiresIn}}.</p>`,
+	),
+	TemplateUnlockAccount: newTemplate(
+		"Your account has been locked",
+		"Hello {{.FullName}},\n\nWe locked your account for {{.LockedFor}} after too many failed login attempts.\n\nIf this was you, use this token to unlock it now: {{.Token}}\n\nIf it was not you, consider resetting your password.\n",
+		`<p>Hello {{.FullName}},</p><p>We locked your account for {{.LockedFor}} after too many failed login attempts.</p><p>If this was you, use this token to unlock it now:</p><p><b>{{.Token}}</b></p><p>If it was not you, consider resetting your password.</p>`,
+	),
+	TemplateWorkspaceInvite: newTemplate(
+		"{{.InviterName}} invited you to {{.WorkspaceName}}",
+		"Hello,\n\n{{.InviterName}} invited you to join the workspace {{.WorkspaceName}} as {{.Role}}.\n\nSign in with this email address and use this token to accept: {{.Token}}\n\nThe invitation expires in {{.ExpiresIn}}.\n",
+		`<p>Hello,</p><p>{{.InviterName}} invited you to join the workspace <b>{{.WorkspaceName}}</b> as {{.Role}}.</p><p>Sign in with this email address and use this token to accept:</p><p><b>{{.Token}}</b></p><p>The invitation expires in {{.ExpiresIn}}.</p>`,
+	),
+	TemplateAccountEvent: newTemplate(
+		"{{.Subject}}",
+		"Hello {{.FullName}},\n\n{{.Message}}\n",
+		`<p>Hello {{.FullName}},</p><p>{{.Message}}</p>`,
+	),
+}
+
+func newTemplate(subject, text, html string) emailTemplate {
+	return emailTemplate{
+		subject: texttemplate.Must(texttemplate.New("subject").Parse(subject)),
+		text:    texttemplate.Must(texttemplate.New("text").Parse(text)),
+		html:    htmltemplate.Must(htmltemplate.Must(htmltemplate.New("layout").Parse(htmlLayout)).New("content").Parse(html)),
+	}
+}
+
+// Render executes the named template with data and returns the resulting message without recipients
+func Render(name string, data map[string]interface{}) (Message, error) {
+	tmpl, ok := templates[name]
+	if !ok {
+		return Message{}, fmt.Errorf("unknown email template %q", name)
+	}
+
+	values := map[string]interface{}{"AppName": config.AppName}
+	for key, value := range data {
+		values[key] = value
+	}
+
+	var subject, text, html bytes.Buffer
+	if err := tmpl.subject.Execute(&subject, values); err != nil {
+		return Message{}, err
+	}
+	if err := tmpl.text.Execute(&text, values); err != nil {
+		return Message{}, err
+	}
+	if err := tmpl.html.ExecuteTemplate(&html, "layout", values); err != nil {
+		return Message{}, err
+	}
+
+	return Message{
+		Subject: subject.String(),
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
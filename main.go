@@ -5,7 +5,11 @@ import (
 	"log"
 	_ "material_todo_go/config"
 	"material_todo_go/database"
+	"material_todo_go/jobs"
+	"material_todo_go/limiter"
+	"material_todo_go/mailer"
 	"material_todo_go/routes"
+	"material_todo_go/utils"
 )
 
 func main() {
@@ -15,6 +19,32 @@ func main() {
 	// Initialize database
 	database.ConnectDB()
 
+	// Load JWT signing keys, refusing to start without a usable key
+	if err := utils.InitKeyring(database.KeyStore{}); err != nil {
+		log.Fatalf("❌ Failed to load JWT signing keys: %v", err)
+	}
+
+	// Load the password policy
+	if err := utils.InitPasswordPolicy(); err != nil {
+		log.Fatalf("❌ Failed to load password policy: %v", err)
+	}
+
+	// Initialize failed login tracking
+	if err := limiter.Setup(); err != nil {
+		log.Fatalf("❌ Failed to configure login limiter: %v", err)
+	}
+
+	// Initialize outbound mail
+	if err := mailer.Setup(); err != nil {
+		log.Fatalf("❌ Failed to configure mailer: %v", err)
+	}
+
+	// Purge accounts whose deletion grace period has ended
+	jobs.StartAccountPurge()
+
+	// Notify users about tasks due soon
+	jobs.StartDueSoonNotifications()
+
 	// Setup routes
 	routes.SetupRoutes(r)
 
@@ -0,0 +1,186 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"material_todo_go/audit"
+	"material_todo_go/database"
+	"material_todo_go/models"
+	"material_todo_go/utils"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Context keys set by AuthRequired
+const (
+	userKey   = "user"
+	claimsKey = "claims"
+	scopesKey = "scopes"
+)
+
+// PersonalTokenPrefix starts every personal access token so it can be told apart from a JWT
+const PersonalTokenPrefix = "mtd_pat_"
+
+// Scopes that can be granted to personal access tokens
+var Scopes = []string{"tasks:read", "tasks:write", "notes:read", "notes:write", "user:read", "user:write"}
+
+// BearerToken returns the token from a "Bearer <token>" Authorization header, or "" if missing
+func BearerToken(c *gin.Context) string {
+	parts := strings.Split(c.GetHeader("Authorization"), " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return ""
+	}
+	return parts[1]
+}
+
+// AuthRequired validates the Bearer token, loads the user once and stores it in the request context.
+// Both access tokens and personal access tokens are accepted.
+func AuthRequired() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization token required"})
+			return
+		}
+
+		token := BearerToken(c)
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid Authorization header format"})
+			return
+		}
+
+		if strings.HasPrefix(token, PersonalTokenPrefix) {
+			authenticatePersonalToken(c, token)
+			return
+		}
+
+		claims, err := utils.ParseJWTClaims(token)
+		if err != nil {
+			audit.Record(c, audit.Entry{Event: audit.EventTokenRejected, Metadata: map[string]interface{}{"path": c.FullPath()}})
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+
+		var user models.User
+		if err := database.DB.Where("email = ?", claims.Email).First(&user).Error; err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+			return
+		}
+
+		if user.Disabled {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Account is disabled"})
+			return
+		}
+
+		if user.PasswordResetRequired {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Password reset required"})
+			return
+		}
+
+		// Session activity does not need to be more precise than a minute
+		database.DB.Model(&models.Session{}).
+			Where("id = ? AND last_seen_at < ?", claims.SessionID, time.Now().Add(-time.Minute)).
+			Update("last_seen_at", time.Now())
+
+		c.Set(userKey, user)
+		c.Set(claimsKey, claims)
+		c.Next()
+	}
+}
+
+// authenticatePersonalToken loads the user of a personal access token and records its use
+func authenticatePersonalToken(c *gin.Context, token string) {
+	var pat models.PersonalAccessToken
+	if err := database.DB.Where("token_hash = ? AND revoked_at IS NULL", utils.HashToken(token)).First(&pat).Error; err != nil {
+		audit.Record(c, audit.Entry{Event: audit.EventTokenRejected, Metadata: map[string]interface{}{"path": c.FullPath(), "type": "personal_access_token"}})
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		return
+	}
+
+	if pat.ExpiresAt != nil && time.Now().After(*pat.ExpiresAt) {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Token expired"})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, pat.UserID).Error; err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	if user.Disabled {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Account is disabled"})
+		return
+	}
+
+	if user.PasswordResetRequired {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Password reset required"})
+		return
+	}
+
+	// Last-used tracking does not need to be more precise than a minute
+	if pat.LastUsedAt == nil || time.Since(*pat.LastUsedAt) > time.Minute {
+		database.DB.Model(&pat).Update("last_used_at", time.Now())
+	}
+
+	c.Set(userKey, user)
+	c.Set(scopesKey, strings.Fields(pat.Scopes))
+	c.Next()
+}
+
+// RequireScope limits personal access tokens to "<resource>:read" for GET requests and
+// "<resource>:write" otherwise. Access tokens from Login are not restricted.
+func RequireScope(resource string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, ok := c.Get(scopesKey)
+		if !ok {
+			c.Next()
+			return
+		}
+
+		required := resource + ":write"
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			required = resource + ":read"
+		}
+
+		for _, scope := range value.([]string) {
+			if scope == required {
+				c.Next()
+				return
+			}
+		}
+
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Token is missing the " + required + " scope"})
+	}
+}
+
+// RequireSession rejects personal access tokens, for endpoints that manage credentials
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get(claimsKey); !ok {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "This endpoint requires a login session"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireAdmin only lets users with the admin role through
+func RequireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if CurrentUser(c).Role != models.RoleAdmin {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// CurrentUser returns the user loaded by AuthRequired
+func CurrentUser(c *gin.Context) models.User {
+	return c.MustGet(userKey).(models.User)
+}
+
+// CurrentClaims returns the access token claims validated by AuthRequired
+func CurrentClaims(c *gin.Context) *utils.Claims {
+	return c.MustGet(claimsKey).(*utils.Claims)
+}
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"material_todo_go/database"
+	"material_todo_go/models"
+	"net/http"
+)
+
+// Context keys set by ActiveWorkspace
+const (
+	workspaceKey     = "workspace"
+	workspaceRoleKey = "workspace_role"
+)
+
+// WorkspaceHeader lets a request work in another workspace than the user's active one
+const WorkspaceHeader = "X-Workspace-ID"
+
+// workspaceRoleRank orders workspace roles so a required role also admits the ones above it
+var workspaceRoleRank = map[string]int{
+	models.WorkspaceRoleViewer: 1,
+	models.WorkspaceRoleMember: 2,
+	models.WorkspaceRoleAdmin:  3,
+	models.WorkspaceRoleOwner:  4,
+}
+
+// WorkspaceRoleAtLeast reports whether role grants at least the permissions of required
+func WorkspaceRoleAtLeast(role, required string) bool {
+	return workspaceRoleRank[role] >= workspaceRoleRank[required]
+}
+
+// ActiveWorkspace resolves the workspace of the request: the X-Workspace-ID header if present,
+// otherwise the user's active workspace, falling back to their personal one. Must run after AuthRequired.
+func ActiveWorkspace() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := CurrentUser(c)
+
+		var workspaceID interface{} = user.ActiveWorkspaceID
+		header := c.GetHeader(WorkspaceHeader)
+		explicit := header != ""
+		if explicit {
+			workspaceID = header
+		}
+
+		var member models.WorkspaceMember
+		err := database.DB.Where("workspace_id = ? AND user_id = ?", workspaceID, user.ID).First(&member).Error
+		if err != nil && explicit {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "You are not a member of this workspace"})
+			return
+		}
+
+		// The active workspace may have been deleted or the user removed from it
+		if err != nil {
+			personal := database.DB.Model(&models.Workspace{}).Select("id").Where("owner_id = ? AND personal = ?", user.ID, true)
+			if err := database.DB.Where("workspace_id IN (?) AND user_id = ?", personal, user.ID).First(&member).Error; err != nil {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "No workspace available"})
+				return
+			}
+		}
+
+		var workspace models.Workspace
+		if err := database.DB.First(&workspace, member.WorkspaceID).Error; err != nil {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "No workspace available"})
+			return
+		}
+
+		c.Set(workspaceKey, workspace)
+		c.Set(workspaceRoleKey, member.Role)
+		c.Next()
+	}
+}
+
+// CurrentWorkspace returns the workspace resolved by ActiveWorkspace
+func CurrentWorkspace(c *gin.Context) models.Workspace {
+	return c.MustGet(workspaceKey).(models.Workspace)
+}
+
+// CurrentWorkspaceRole returns the role of the user in the workspace resolved by ActiveWorkspace
+func CurrentWorkspaceRole(c *gin.Context) string {
+	return c.MustGet(workspaceRoleKey).(string)
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// AuditEvent is one entry of the append-only security log. Each entry stores the hash of the
+// previous one, so editing or removing a row breaks the chain and shows up in verification.
+type AuditEvent struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Event     string    `json:"event" gorm:"index;not null"`
+	ActorID   uint      `json:"actor_id" gorm:"index"`   // user performing the action, 0 if unknown
+	SubjectID uint      `json:"subject_id" gorm:"index"` // user the action applies to, 0 if none
+	Email     string    `json:"email"`                   // email given in the request, for failures without a user
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	Metadata  string    `json:"metadata" gorm:"type:text"` // JSON object
+	PrevHash  string    `json:"prev_hash"`
+	Hash      string    `json:"hash" gorm:"uniqueIndex;not null"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
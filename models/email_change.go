@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// EmailChange is a pending switch to NewEmail, applied once the emailed token is confirmed.
+type EmailChange struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"index;not null"`
+	NewEmail  string    `json:"new_email" gorm:"not null"`
+	TokenHash string    `json:"-" gorm:"uniqueIndex;not null"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
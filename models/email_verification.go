@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// EmailVerification stores a hashed token emailed to confirm a user's address.
+type EmailVerification struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"index;not null"`
+	TokenHash string    `json:"-" gorm:"uniqueIndex;not null"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// LoginAttempt counts recent failures for a rate limited key such as an account or an IP.
+type LoginAttempt struct {
+	Key           string    `json:"key" gorm:"primaryKey"`
+	Failures      int       `json:"failures" gorm:"not null"`
+	LastFailureAt time.Time `json:"last_failure_at" gorm:"index;not null"`
+}
@@ -5,6 +5,7 @@ import "gorm.io/gorm"
 type Note struct {
 	ID          uint   `json:"id" gorm:"primaryKey"`
 	UserID      uint   `json:"user_id"`
+	WorkspaceID uint   `json:"workspace_id" gorm:"index"`
 	Title       string `json:"title"`
 	Description string `json:"description"`
 	gorm.Model
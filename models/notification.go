@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// Notification types
+const (
+	NotificationMention         = "mention"
+	NotificationAssignment      = "assignment"
+	NotificationDueSoon         = "due_soon"
+	NotificationTaskGroupShared = "task_group_shared"
+)
+
+// Entity types a notification can point at
+const (
+	NotificationEntityTask      = "task"
+	NotificationEntityNote      = "note"
+	NotificationEntityTaskGroup = "task_group"
+)
+
+// Notification is an entry in a user's in-app notification center, pointing at the task, note or task group it is about
+type Notification struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	UserID     uint       `json:"user_id" gorm:"index;not null"`
+	Type       string     `json:"type" gorm:"index;not null"`
+	ActorID    uint       `json:"actor_id"` // 0 for system notifications
+	Message    string     `json:"message" gorm:"not null"`
+	EntityType string     `json:"entity_type"` // task, note or task_group
+	EntityID   uint       `json:"entity_id"`
+	ReadAt     *time.Time `json:"read_at"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"index"`
+}
+
+// NotificationPreference overrides how a user receives one type of notification.
+// Without a row, notifications are shown in-app and not emailed.
+type NotificationPreference struct {
+	ID     uint   `json:"-" gorm:"primaryKey"`
+	UserID uint   `json:"-" gorm:"uniqueIndex:idx_notification_preference;not null"`
+	Type   string `json:"type" gorm:"uniqueIndex:idx_notification_preference;not null"`
+	InApp  bool   `json:"in_app" gorm:"not null"`
+	Email  bool   `json:"email" gorm:"not null"`
+}
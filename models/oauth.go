@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// OAuthIdentity links a provider account to a user.
+type OAuthIdentity struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"index;not null"`
+	Provider  string    `json:"provider" gorm:"uniqueIndex:idx_oauth_provider_subject;not null"`
+	Subject   string    `json:"subject" gorm:"uniqueIndex:idx_oauth_provider_subject;not null"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// OAuthLogin tracks one social login attempt: the provider state and PKCE verifier,
+// then the one-time login code handed to the client after the callback.
+type OAuthLogin struct {
+	ID                uint       `json:"id" gorm:"primaryKey"`
+	Provider          string     `json:"provider" gorm:"not null"`
+	StateHash         string     `json:"-" gorm:"uniqueIndex;not null"`
+	ProviderVerifier  string     `json:"-" gorm:"not null"`
+	ClientRedirectURI string     `json:"client_redirect_uri" gorm:"not null"`
+	ClientState       string     `json:"-"`
+	ClientChallenge   string     `json:"-" gorm:"not null"`
+	LoginCodeHash     string     `json:"-" gorm:"index"`
+	UserID            uint       `json:"user_id"`
+	ExpiresAt         time.Time  `json:"expires_at" gorm:"not null"`
+	UsedAt            *time.Time `json:"used_at"`
+	CreatedAt         time.Time  `json:"created_at"`
+}
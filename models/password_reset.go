@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// PasswordReset stores a hashed, single-use reset code issued by SendResetCode.
+type PasswordReset struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"index;not null"`
+	CodeHash  string    `json:"-" gorm:"not null"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null"`
+	Attempts  int       `json:"attempts" gorm:"not null;default:0"`
+	Used      bool      `json:"used" gorm:"not null;default:false"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// PersonalAccessToken is a long-lived, scoped credential for scripts and integrations.
+// Only the hash of the token is stored; Prefix helps users recognise it in listings.
+type PersonalAccessToken struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	UserID     uint       `json:"user_id" gorm:"index;not null"`
+	Name       string     `json:"name" gorm:"not null"`
+	TokenHash  string     `json:"-" gorm:"uniqueIndex;not null"`
+	Prefix     string     `json:"prefix" gorm:"not null"`
+	Scopes     string     `json:"scopes" gorm:"not null"` // space separated
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
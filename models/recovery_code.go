@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// RecoveryCode is a hashed one-time code that replaces a TOTP code when the authenticator is lost.
+type RecoveryCode struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"index;not null"`
+	CodeHash  string     `json:"-" gorm:"index;not null"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
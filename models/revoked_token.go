@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// RevokedToken records the jti of an access token that was revoked before it expired.
+type RevokedToken struct {
+	JTI       string    `json:"jti" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"index;not null"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"index;not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// SigningKey is a generated asymmetric JWT signing key, identified by its kid.
+type SigningKey struct {
+	ID         string     `json:"id" gorm:"primaryKey"`
+	Algorithm  string     `json:"algorithm" gorm:"not null"`
+	PrivateKey string     `json:"-" gorm:"type:text;not null"`
+	CreatedAt  time.Time  `json:"created_at"`
+	RetiredAt  *time.Time `json:"retired_at"`
+}
@@ -11,10 +11,16 @@ type Task struct {
 	Description string         `json:"description"`
 	TaskGroupID uint           `json:"task_group_id"`
 	TaskGroup   TaskGroup      `json:"task_group" gorm:"foreignKey:TaskGroupID"`
+	UserID      uint           `json:"user_id" gorm:"index"` // owner, same as the owner of the task group
+	Assignees   []TaskAssignee `json:"-" gorm:"foreignKey:TaskID"`
 	StartDate   time.Time      `json:"start_date"`
 	FinishDate  time.Time      `json:"finish_date"`
 	Status      string         `json:"status"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+
+	// Set once the due soon notification went out, cleared when the finish date changes
+	DueSoonNotifiedAt *time.Time `json:"-"`
+
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 }
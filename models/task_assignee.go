@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// TaskAssignee makes a user responsible for a task
+type TaskAssignee struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	TaskID       uint      `json:"task_id" gorm:"uniqueIndex:idx_task_assignee;not null"`
+	UserID       uint      `json:"user_id" gorm:"uniqueIndex:idx_task_assignee;index;not null"`
+	User         User      `json:"-" gorm:"foreignKey:UserID"`
+	AssignedByID uint      `json:"assigned_by_id"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// TaskHistory records a change to a field of a task, with the old and new values as text
+type TaskHistory struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	TaskID    uint      `json:"task_id" gorm:"index;not null"`
+	ActorID   uint      `json:"actor_id" gorm:"not null"`
+	Field     string    `json:"field" gorm:"not null"`
+	OldValue  string    `json:"old_value"`
+	NewValue  string    `json:"new_value"`
+	CreatedAt time.Time `json:"created_at"`
+}
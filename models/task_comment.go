@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// TaskComment is a markdown comment on a task. Replies point to the top-level comment of their thread.
+// A deleted comment that still has replies stays as an empty placeholder so the thread keeps its shape.
+type TaskComment struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	TaskID    uint       `json:"task_id" gorm:"index;not null"`
+	AuthorID  uint       `json:"author_id" gorm:"index;not null"`
+	Author    User       `json:"-" gorm:"foreignKey:AuthorID"`
+	ParentID  *uint      `json:"parent_id" gorm:"index"`
+	Body      string     `json:"body" gorm:"type:text;not null"`
+	Deleted   bool       `json:"deleted" gorm:"not null;default:false"`
+	EditedAt  *time.Time `json:"edited_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
@@ -13,4 +13,5 @@ type TaskGroup struct {
 	BackgroundColor string `json:"background_color" gorm:"not null"`
 	IconColor       string `json:"icon_color" gorm:"not null"`
 	UserID          uint   `json:"user_id" gorm:"not null"` // Associate with a user
+	WorkspaceID     uint   `json:"workspace_id" gorm:"index"`
 }
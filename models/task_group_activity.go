@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Task group activity actions
+const (
+	ActivityGroupCreated  = "group_created"
+	ActivityGroupUpdated  = "group_updated"
+	ActivityGroupDeleted  = "group_deleted"
+	ActivityMemberAdded   = "member_added"
+	ActivityMemberRemoved = "member_removed"
+	ActivityMemberRole    = "member_role_changed"
+	ActivityTaskCreated   = "task_created"
+	ActivityTaskUpdated   = "task_updated"
+	ActivityTaskMovedIn   = "task_moved_in"
+	ActivityTaskMovedOut  = "task_moved_out"
+	ActivityTaskCompleted = "task_completed"
+	ActivityTaskDeleted   = "task_deleted"
+)
+
+// TaskGroupActivity is one entry of the chronological feed of a task group
+type TaskGroupActivity struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	TaskGroupID uint      `json:"task_group_id" gorm:"index;not null"`
+	ActorID     uint      `json:"actor_id" gorm:"not null"`
+	Actor       User      `json:"-" gorm:"foreignKey:ActorID"`
+	Action      string    `json:"action" gorm:"not null"`
+	TaskID      *uint     `json:"task_id"`
+	Metadata    string    `json:"metadata" gorm:"type:text"` // JSON object
+	CreatedAt   time.Time `json:"created_at"`
+}
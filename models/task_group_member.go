@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Task group roles, from least to most privileged
+const (
+	TaskGroupViewer = "viewer"
+	TaskGroupEditor = "editor"
+	TaskGroupOwner  = "owner"
+)
+
+// TaskGroupMember gives another user access to a task group. The owner is TaskGroup.UserID
+// and has no membership row.
+type TaskGroupMember struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	TaskGroupID uint      `json:"task_group_id" gorm:"uniqueIndex:idx_task_group_member;not null"`
+	UserID      uint      `json:"user_id" gorm:"uniqueIndex:idx_task_group_member;index;not null"`
+	Role        string    `json:"role" gorm:"not null"`
+	CreatedAt   time.Time `json:"created_at"`
+}
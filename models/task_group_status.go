@@ -0,0 +1,27 @@
+package models
+
+// Status categories; completion rates count the tasks in "done" statuses
+const (
+	StatusCategoryTodo       = "todo"
+	StatusCategoryInProgress = "in_progress"
+	StatusCategoryDone       = "done"
+)
+
+// TaskGroupStatus is one of the ordered statuses tasks of a group can be in
+type TaskGroupStatus struct {
+	ID          uint   `json:"id" gorm:"primaryKey"`
+	TaskGroupID uint   `json:"task_group_id" gorm:"uniqueIndex:idx_task_group_status;not null"`
+	Name        string `json:"name" gorm:"uniqueIndex:idx_task_group_status;not null"`
+	Position    int    `json:"position" gorm:"not null"`
+	Category    string `json:"category" gorm:"not null"`
+	WIPLimit    int    `json:"wip_limit" gorm:"not null;default:0"` // 0 means no limit
+}
+
+// TaskGroupTransition allows tasks to move from one status to another. A group without
+// transitions allows every move.
+type TaskGroupTransition struct {
+	ID          uint   `json:"-" gorm:"primaryKey"`
+	TaskGroupID uint   `json:"-" gorm:"index;not null"`
+	FromStatus  string `json:"from" gorm:"not null"`
+	ToStatus    string `json:"to" gorm:"not null"`
+}
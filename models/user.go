@@ -1,9 +1,37 @@
 package models
 
+import "time"
+
+// User roles
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 type User struct {
-	ID       uint   `json:"id" gorm:"primaryKey"`
-	FullName string `json:"full_name"`
-	Email    string `json:"email" gorm:"unique"`
-	Password string `json:"password"`
-	Image    string `json:"image"`
+	ID            uint   `json:"id" gorm:"primaryKey"`
+	FullName      string `json:"full_name"`
+	Email         string `json:"email" gorm:"unique"`
+	Password      string `json:"password"`
+	Image         string `json:"image"`
+	EmailVerified bool   `json:"email_verified" gorm:"not null;default:false"`
+	Role          string `json:"role" gorm:"not null;default:user"`
+	Disabled      bool   `json:"disabled" gorm:"not null;default:false"`
+
+	// Set by an admin; Login is refused until the password is reset
+	PasswordResetRequired bool `json:"password_reset_required" gorm:"not null;default:false"`
+
+	// Access tokens issued at or before this moment are rejected
+	TokensValidAfter *time.Time `json:"-"`
+
+	// Two-factor authentication; the secret is stored at enrollment and enabled once confirmed
+	TOTPSecret      string `json:"-"`
+	TOTPEnabled     bool   `json:"two_factor_enabled" gorm:"not null;default:false"`
+	TOTPLastCounter int64  `json:"-"`
+
+	// Set when the user asked to delete the account; it is purged once this moment passes
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at"`
+
+	// Workspace the client works in unless a request names another one with X-Workspace-ID
+	ActiveWorkspaceID uint `json:"active_workspace_id"`
 }
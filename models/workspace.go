@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// Workspace roles, from least to most privileged
+const (
+	WorkspaceRoleViewer = "viewer"
+	WorkspaceRoleMember = "member"
+	WorkspaceRoleAdmin  = "admin"
+	WorkspaceRoleOwner  = "owner"
+)
+
+// Workspace owns task groups and notes and is shared by its members.
+// Every user has one personal workspace, created at signup, that cannot be shared or deleted.
+type Workspace struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name" gorm:"not null"`
+	OwnerID   uint      `json:"owner_id" gorm:"index;not null"`
+	Personal  bool      `json:"personal" gorm:"not null;default:false"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// WorkspaceMember gives a user a role in a workspace; the owner has a membership too
+type WorkspaceMember struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	WorkspaceID uint      `json:"workspace_id" gorm:"uniqueIndex:idx_workspace_member;not null"`
+	UserID      uint      `json:"user_id" gorm:"uniqueIndex:idx_workspace_member;index;not null"`
+	Role        string    `json:"role" gorm:"not null"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// WorkspaceInvitation is an emailed, single-use invitation to join a workspace
+type WorkspaceInvitation struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	WorkspaceID uint       `json:"workspace_id" gorm:"index;not null"`
+	Email       string     `json:"email" gorm:"not null"`
+	Role        string     `json:"role" gorm:"not null"`
+	TokenHash   string     `json:"-" gorm:"uniqueIndex;not null"`
+	InvitedByID uint       `json:"invited_by_id" gorm:"not null"`
+	ExpiresAt   time.Time  `json:"expires_at" gorm:"not null"`
+	AcceptedAt  *time.Time `json:"accepted_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
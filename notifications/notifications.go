@@ -0,0 +1,117 @@
+package notifications
+
+import (
+	"log"
+	"material_todo_go/database"
+	"material_todo_go/mailer"
+	"material_todo_go/models"
+	"regexp"
+	"strings"
+)
+
+// Entity types a notification can point at
+const (
+	EntityTask      = models.NotificationEntityTask
+	EntityNote      = models.NotificationEntityNote
+	EntityTaskGroup = models.NotificationEntityTaskGroup
+)
+
+// Types lists every notification type users can set preferences for
+var Types = []string{
+	models.NotificationMention,
+	models.NotificationAssignment,
+	models.NotificationDueSoon,
+	models.NotificationTaskGroupShared,
+}
+
+// mentionPattern matches "@" followed by the email of a user, e.g. "@jane@example.com"
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w.])@([\w.%+-]+@[\w-]+(?:\.[\w-]+)*\.[A-Za-z]{2,})`)
+
+// Mentions returns the lowercased emails mentioned in a text, without duplicates
+func Mentions(text string) []string {
+	seen := map[string]bool{}
+	emails := []string{}
+	for _, match := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		email := strings.ToLower(match[1])
+		if !seen[email] {
+			seen[email] = true
+			emails = append(emails, email)
+		}
+	}
+	return emails
+}
+
+// NewMentions returns the emails mentioned in text that were not already mentioned in previous
+func NewMentions(previous, text string) []string {
+	before := map[string]bool{}
+	for _, email := range Mentions(previous) {
+		before[email] = true
+	}
+
+	emails := []string{}
+	for _, email := range Mentions(text) {
+		if !before[email] {
+			emails = append(emails, email)
+		}
+	}
+	return emails
+}
+
+// Preference returns how the user wants to receive a notification type
+func Preference(userID uint, notificationType string) models.NotificationPreference {
+	preference := models.NotificationPreference{UserID: userID, Type: notificationType, InApp: true}
+	database.DB.Where("user_id = ? AND type = ?", userID, notificationType).Limit(1).Find(&preference)
+	return preference
+}
+
+// Send notifies a user according to their preferences. Actors are never notified of their own actions.
+// Failures are logged, they never fail the action that caused the notification.
+func Send(notification models.Notification) {
+	if notification.UserID == 0 || notification.UserID == notification.ActorID {
+		return
+	}
+
+	preference := Preference(notification.UserID, notification.Type)
+	if preference.InApp {
+		if err := database.DB.Create(&notification).Error; err != nil {
+			log.Printf("Failed to store %s notification for user %d: %v", notification.Type, notification.UserID, err)
+		}
+	}
+
+	if preference.Email {
+		var user models.User
+		if err := database.DB.First(&user, notification.UserID).Error; err != nil {
+			return
+		}
+		if err := mailer.SendTemplate(user.Email, mailer.TemplateAccountEvent, map[string]interface{}{
+			"FullName": user.FullName,
+			"Subject":  notification.Message,
+			"Message":  notification.Message,
+		}); err != nil {
+			log.Printf("Failed to email %s notification to %s: %v", notification.Type, user.Email, err)
+		}
+	}
+}
+
+// SendMentions notifies the users mentioned by email who pass canAccess
+func SendMentions(emails []string, canAccess func(userID uint) bool, actor models.User, where string, entityType string, entityID uint) {
+	if len(emails) == 0 {
+		return
+	}
+
+	var users []models.User
+	database.DB.Where("LOWER(email) IN ?", emails).Find(&users)
+	for _, user := range users {
+		if !canAccess(user.ID) {
+			continue
+		}
+		Send(models.Notification{
+			UserID:     user.ID,
+			Type:       models.NotificationMention,
+			ActorID:    actor.ID,
+			Message:    actor.FullName + " mentioned you in " + where,
+			EntityType: entityType,
+			EntityID:   entityID,
+		})
+	}
+}
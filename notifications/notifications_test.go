@@ -0,0 +1,56 @@
+package notifications
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMentions(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"none", "nothing to see here", []string{}},
+		{"at the start", "@jane@example.com please review", []string{"jane@example.com"}},
+		{"in a sentence", "thanks, @jane@example.com!", []string{"jane@example.com"}},
+		{"trailing period", "ask @jane@example.com.", []string{"jane@example.com"}},
+		{"subdomain and plus", "cc @j.doe+todo@mail.example.co.uk", []string{"j.doe+todo@mail.example.co.uk"}},
+		{"lowercased", "@Jane@Example.COM", []string{"jane@example.com"}},
+		{"duplicates once, in order", "@b@example.com @a@example.com @B@example.com", []string{"b@example.com", "a@example.com"}},
+		{"plain email is not a mention", "mail jane@example.com", []string{}},
+		{"inside a word", "foo@jane@example.com", []string{}},
+		{"handle without a domain", "@jane", []string{}},
+		{"domain without a tld", "@jane@localhost", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Mentions(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Mentions(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewMentions(t *testing.T) {
+	tests := []struct {
+		name     string
+		previous string
+		text     string
+		want     []string
+	}{
+		{"new text", "", "hi @a@example.com", []string{"a@example.com"}},
+		{"unchanged", "hi @a@example.com", "hi @a@example.com, updated", []string{}},
+		{"added mention", "hi @a@example.com", "hi @a@example.com and @b@example.com", []string{"b@example.com"}},
+		{"removed mention", "hi @a@example.com and @b@example.com", "hi @a@example.com", []string{}},
+		{"case change is not new", "hi @a@example.com", "hi @A@EXAMPLE.com", []string{}},
+		{"mention re-added after removal", "no one", "hi @a@example.com", []string{"a@example.com"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewMentions(tt.previous, tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NewMentions(%q, %q) = %v, want %v", tt.previous, tt.text, got, tt.want)
+			}
+		})
+	}
+}
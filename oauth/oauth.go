@@ -0,0 +1,176 @@
+package oauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"material_todo_go/config"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Profile is the identity returned by a provider after login
+type Profile struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// ChallengeS256 returns the PKCE S256 code challenge for a verifier
+func ChallengeS256(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthCodeURL builds the provider authorization URL for the authorization-code flow with PKCE
+func AuthCodeURL(p config.OAuthProvider, state, verifier string) string {
+	values := url.Values{}
+	values.Set("response_type", "code")
+	values.Set("client_id", p.ClientID)
+	values.Set("redirect_uri", p.RedirectURL)
+	values.Set("scope", strings.Join(p.Scopes, " "))
+	values.Set("state", state)
+	values.Set("code_challenge", ChallengeS256(verifier))
+	values.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(p.AuthURL, "?") {
+		separator = "&"
+	}
+	return p.AuthURL + separator + values.Encode()
+}
+
+// Exchange trades an authorization code for the provider access token
+func Exchange(ctx context.Context, p config.OAuthProvider, code, verifier string) (string, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.RedirectURL)
+	form.Set("client_id", p.ClientID)
+	form.Set("client_secret", p.ClientSecret)
+	form.Set("code_verifier", verifier)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	var response struct {
+		AccessToken string `json:"access_token"`
+		Error       string `json:"error"`
+	}
+	if err := doJSON(req, &response); err != nil {
+		return "", err
+	}
+	if response.AccessToken == "" {
+		return "", fmt.Errorf("token exchange failed: %s", response.Error)
+	}
+	return response.AccessToken, nil
+}
+
+// FetchProfile loads the user's identity with the provider access token
+func FetchProfile(ctx context.Context, p config.OAuthProvider, accessToken string) (Profile, error) {
+	if p.Type == "github" {
+		return fetchGitHubProfile(ctx, p, accessToken)
+	}
+
+	// Standard OpenID Connect userinfo response
+	var info struct {
+		Subject       string      `json:"sub"`
+		Email         string      `json:"email"`
+		EmailVerified interface{} `json:"email_verified"`
+		Name          string      `json:"name"`
+	}
+	if err := getJSON(ctx, p.UserInfoURL, accessToken, &info); err != nil {
+		return Profile{}, err
+	}
+	if info.Subject == "" {
+		return Profile{}, errors.New("userinfo response has no subject")
+	}
+
+	// Some providers send email_verified as a string
+	verified := false
+	switch value := info.EmailVerified.(type) {
+	case bool:
+		verified = value
+	case string:
+		verified, _ = strconv.ParseBool(value)
+	}
+
+	return Profile{Subject: info.Subject, Email: info.Email, EmailVerified: verified, Name: info.Name}, nil
+}
+
+// fetchGitHubProfile reads /user and the primary verified address from /user/emails
+func fetchGitHubProfile(ctx context.Context, p config.OAuthProvider, accessToken string) (Profile, error) {
+	var info struct {
+		ID    int64  `json:"id"`
+		Login string `json:"login"`
+		Name  string `json:"name"`
+	}
+	if err := getJSON(ctx, p.UserInfoURL, accessToken, &info); err != nil {
+		return Profile{}, err
+	}
+	if info.ID == 0 {
+		return Profile{}, errors.New("user response has no id")
+	}
+
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := getJSON(ctx, p.EmailsURL, accessToken, &emails); err != nil {
+		return Profile{}, err
+	}
+
+	profile := Profile{Subject: strconv.FormatInt(info.ID, 10), Name: info.Name}
+	if profile.Name == "" {
+		profile.Name = info.Login
+	}
+	for _, email := range emails {
+		if email.Primary {
+			profile.Email = email.Email
+			profile.EmailVerified = email.Verified
+		}
+	}
+	return profile, nil
+}
+
+func getJSON(ctx context.Context, endpoint, accessToken string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", "application/json")
+	return doJSON(req, out)
+}
+
+func doJSON(req *http.Request, out interface{}) error {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s returned %d", req.URL.Host, resp.StatusCode)
+	}
+	return json.Unmarshal(body, out)
+}
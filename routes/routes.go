@@ -3,28 +3,59 @@ package routes
 import (
 	"github.com/gin-gonic/gin"
 	"material_todo_go/controllers"
+	"material_todo_go/middleware"
 )
 
 func SetupRoutes(r *gin.Engine) {
+	r.GET("/.well-known/jwks.json", controllers.GetJWKS)
+
 	apiLogin := r.Group("/api/auth")
 	{
 		apiLogin.POST("/login", controllers.Login)
+		apiLogin.POST("/login/2fa", controllers.LoginTwoFactor)
 		apiLogin.POST("/signup", controllers.Signup)
 		apiLogin.POST("/forget-password/generateCode", controllers.SendResetCode)
 		apiLogin.POST("/forget-password/changePassword", controllers.ResetPassword)
 		apiLogin.POST("/validate-token", controllers.ValidateToken)
+		apiLogin.POST("/refresh", controllers.RefreshToken)
+		apiLogin.POST("/logout", middleware.AuthRequired(), middleware.RequireSession(), controllers.Logout)
+		apiLogin.POST("/logout-all", middleware.AuthRequired(), middleware.RequireSession(), controllers.LogoutAll)
+		apiLogin.POST("/verify-email", controllers.VerifyEmail)
+		apiLogin.POST("/verify-email/resend", controllers.ResendVerification)
+		apiLogin.POST("/unlock-account", controllers.UnlockAccount)
+		apiLogin.POST("/confirm-email-change", controllers.ConfirmEmailChange)
+		apiLogin.GET("/oauth/:provider/authorize", controllers.OAuthAuthorize)
+		apiLogin.GET("/oauth/:provider/callback", controllers.OAuthCallback)
+		apiLogin.POST("/oauth/token", controllers.OAuthToken)
 	}
 	apiPolicy := r.Group("/api/documents")
 	{
 		apiPolicy.GET("/getPolicy", controllers.GetPolicy)
 		apiPolicy.GET("/getPrivacy", controllers.GetPrivacy)
 	}
-	apiUser := r.Group("/api/user")
+	apiUser := r.Group("/api/user", middleware.AuthRequired(), middleware.RequireScope("user"))
 	{
 		apiUser.GET("/getUserInfo", controllers.GetUserInformation)
 		apiUser.PUT("/updateUserInfo", controllers.UpdateUser)
+		apiUser.GET("/audit-events", controllers.GetAuditEvents)
+	}
+	apiAccount := r.Group("/api/user", middleware.AuthRequired(), middleware.RequireSession())
+	{
+		apiAccount.PUT("/change-password", controllers.ChangePassword)
+		apiAccount.POST("/change-email", controllers.RequestEmailChange)
+		apiAccount.POST("/2fa/enroll", controllers.EnrollTwoFactor)
+		apiAccount.POST("/2fa/confirm", controllers.ConfirmTwoFactor)
+		apiAccount.POST("/2fa/disable", controllers.DisableTwoFactor)
+		apiAccount.GET("/tokens", controllers.GetPersonalTokens)
+		apiAccount.POST("/tokens", controllers.CreatePersonalToken)
+		apiAccount.DELETE("/tokens/:id", controllers.RevokePersonalToken)
+		apiAccount.GET("/sessions", controllers.GetSessions)
+		apiAccount.DELETE("/sessions/:id", controllers.RevokeSession)
+		apiAccount.POST("/delete-account", controllers.RequestAccountDeletion)
+		apiAccount.POST("/delete-account/cancel", controllers.CancelAccountDeletion)
+		apiAccount.GET("/export", controllers.ExportAccountData)
 	}
-	apiNotes := r.Group("/api/notes")
+	apiNotes := r.Group("/api/notes", middleware.AuthRequired(), middleware.RequireScope("notes"), middleware.ActiveWorkspace())
 	{
 		apiNotes.POST("/createNote", controllers.CreateNote)
 		apiNotes.GET("/getAllNotes", controllers.GetAllNotes)
@@ -32,15 +63,22 @@ func SetupRoutes(r *gin.Engine) {
 		apiNotes.PUT("/updateNote/:id", controllers.UpdateNote)
 		apiNotes.DELETE("/deleteNote/:id", controllers.DeleteNote)
 	}
-	apiTaskGroup := r.Group("/api/tasks_groups")
+	apiTaskGroup := r.Group("/api/tasks_groups", middleware.AuthRequired(), middleware.RequireScope("tasks"), middleware.ActiveWorkspace())
 	{
 		apiTaskGroup.POST("/createTaskGroup", controllers.CreateTaskGroup)
 		apiTaskGroup.GET("/getTasksGroup", controllers.GetTaskGroups)
 		apiTaskGroup.GET("/getTaskGroup/:id", controllers.GetTaskGroup)
 		apiTaskGroup.PUT("/updateTaskGroup/:id", controllers.UpdateTaskGroup)
 		apiTaskGroup.DELETE("/deleteTaskGroup/:id", controllers.DeleteTaskGroup)
+		apiTaskGroup.GET("/:id/activity", controllers.GetTaskGroupActivity)
+		apiTaskGroup.GET("/:id/workflow", controllers.GetTaskGroupWorkflow)
+		apiTaskGroup.PUT("/:id/workflow", controllers.UpdateTaskGroupWorkflow)
+		apiTaskGroup.GET("/:id/members", controllers.GetTaskGroupMembers)
+		apiTaskGroup.POST("/:id/members", controllers.AddTaskGroupMember)
+		apiTaskGroup.PUT("/:id/members/:user_id", controllers.UpdateTaskGroupMember)
+		apiTaskGroup.DELETE("/:id/members/:user_id", controllers.RemoveTaskGroupMember)
 	}
-	apiTask := r.Group("/api/tasks")
+	apiTask := r.Group("/api/tasks", middleware.AuthRequired(), middleware.RequireScope("tasks"), middleware.ActiveWorkspace())
 	{
 		apiTask.POST("/createTask", controllers.CreateTask)
 		apiTask.GET("/getAllTasks", controllers.GetAllTasks)
@@ -50,5 +88,47 @@ func SetupRoutes(r *gin.Engine) {
 		apiTask.GET("/getTasks/todo", controllers.GetTasksByStatusTODO)
 		apiTask.GET("/getTasks/in_progress", controllers.GetTasksByStatusInProgress)
 		apiTask.GET("/getTask/finish-date", controllers.GetTasksByFinishDate)
+		apiTask.GET("/getTasks/assigned_to_me", controllers.GetTasksAssignedToMe)
+		apiTask.GET("/:id/history", controllers.GetTaskHistory)
+		apiTask.GET("/:id/comments", controllers.GetTaskComments)
+		apiTask.POST("/:id/comments", controllers.CreateTaskComment)
+		apiTask.PUT("/:id/comments/:comment_id", controllers.UpdateTaskComment)
+		apiTask.DELETE("/:id/comments/:comment_id", controllers.DeleteTaskComment)
+	}
+	apiWorkspace := r.Group("/api/workspaces", middleware.AuthRequired(), middleware.RequireScope("user"))
+	{
+		apiWorkspace.GET("", controllers.GetWorkspaces)
+		apiWorkspace.POST("", controllers.CreateWorkspace)
+		apiWorkspace.POST("/invitations/accept", controllers.AcceptWorkspaceInvitation)
+		apiWorkspace.PUT("/:id", controllers.UpdateWorkspace)
+		apiWorkspace.DELETE("/:id", controllers.DeleteWorkspace)
+		apiWorkspace.PUT("/:id/switch", controllers.SwitchWorkspace)
+		apiWorkspace.GET("/:id/members", controllers.GetWorkspaceMembers)
+		apiWorkspace.PUT("/:id/members/:user_id", controllers.UpdateWorkspaceMember)
+		apiWorkspace.DELETE("/:id/members/:user_id", controllers.RemoveWorkspaceMember)
+		apiWorkspace.GET("/:id/invitations", controllers.GetWorkspaceInvitations)
+		apiWorkspace.POST("/:id/invitations", controllers.CreateWorkspaceInvitation)
+		apiWorkspace.DELETE("/:id/invitations/:invitation_id", controllers.RevokeWorkspaceInvitation)
+	}
+	apiNotification := r.Group("/api/notifications", middleware.AuthRequired(), middleware.RequireScope("user"))
+	{
+		apiNotification.GET("", controllers.GetNotifications)
+		apiNotification.GET("/unread-count", controllers.GetUnreadNotificationCount)
+		apiNotification.PUT("/read-all", controllers.MarkAllNotificationsRead)
+		apiNotification.PUT("/:id/read", controllers.MarkNotificationRead)
+		apiNotification.PUT("/:id/unread", controllers.MarkNotificationUnread)
+		apiNotification.GET("/preferences", controllers.GetNotificationPreferences)
+		apiNotification.PUT("/preferences", controllers.UpdateNotificationPreferences)
+	}
+	apiAdmin := r.Group("/api/admin", middleware.AuthRequired(), middleware.RequireSession(), middleware.RequireAdmin())
+	{
+		apiAdmin.GET("/users", controllers.AdminGetUsers)
+		apiAdmin.GET("/users/:id", controllers.AdminGetUser)
+		apiAdmin.PUT("/users/:id/disable", controllers.AdminDisableUser)
+		apiAdmin.PUT("/users/:id/enable", controllers.AdminEnableUser)
+		apiAdmin.PUT("/users/:id/role", controllers.AdminUpdateUserRole)
+		apiAdmin.POST("/users/:id/force-password-reset", controllers.AdminForcePasswordReset)
+		apiAdmin.GET("/audit-events", controllers.AdminGetAuditEvents)
+		apiAdmin.GET("/audit-events/verify", controllers.AdminVerifyAuditLog)
 	}
 }
@@ -1,13 +1,92 @@
 package utils
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"material_todo_go/config"
+	"strings"
+)
 
+// HashPassword hashes with the algorithm and parameters from config (bcrypt or argon2id)
 func HashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
+	if config.PasswordHashAlgorithm == "argon2id" {
+		return hashArgon2id(password)
+	}
+
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), config.BcryptCost)
 	return string(bytes), err
 }
 
 func CheckPasswordHash(password, hash string) bool {
+	if strings.HasPrefix(hash, "$argon2id$") {
+		return checkArgon2id(password, hash)
+	}
+
 	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 	return err == nil
 }
+
+// NeedsRehash reports whether a stored hash was made with another algorithm or other parameters than configured
+func NeedsRehash(hash string) bool {
+	if config.PasswordHashAlgorithm == "argon2id" {
+		memory, iterations, threads, _, _, err := decodeArgon2id(hash)
+		return err != nil || int(memory) != config.Argon2Memory || int(iterations) != config.Argon2Iterations || int(threads) != config.Argon2Threads
+	}
+
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost != config.BcryptCost
+}
+
+const argon2KeyLength = 32
+
+// hashArgon2id encodes the hash in the PHC string format: $argon2id$v=19$m=...,t=...,p=...$salt$key
+func hashArgon2id(password string) (string, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, uint32(config.Argon2Iterations), uint32(config.Argon2Memory), uint8(config.Argon2Threads), argon2KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, config.Argon2Memory, config.Argon2Iterations, config.Argon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func decodeArgon2id(hash string) (memory uint32, iterations uint32, threads uint8, salt []byte, key []byte, err error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return 0, 0, 0, nil, nil, fmt.Errorf("invalid argon2id hash")
+	}
+
+	var version int
+	if _, err = fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return 0, 0, 0, nil, nil, fmt.Errorf("unsupported argon2 version")
+	}
+	if _, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &iterations, &threads); err != nil {
+		return 0, 0, 0, nil, nil, err
+	}
+	if iterations < 1 || threads < 1 {
+		return 0, 0, 0, nil, nil, fmt.Errorf("invalid argon2id parameters")
+	}
+	if salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return 0, 0, 0, nil, nil, err
+	}
+	if key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil {
+		return 0, 0, 0, nil, nil, err
+	}
+	return memory, iterations, threads, salt, key, nil
+}
+
+func checkArgon2id(password, hash string) bool {
+	memory, iterations, threads, salt, key, err := decodeArgon2id(hash)
+	if err != nil {
+		return false
+	}
+
+	computed := argon2.IDKey([]byte(password), salt, iterations, memory, threads, uint32(len(key)))
+	return subtle.ConstantTimeCompare(computed, key) == 1
+}
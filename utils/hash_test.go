@@ -0,0 +1,150 @@
+package utils
+
+import (
+	"material_todo_go/config"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// useHashConfig sets cheap hashing parameters for the duration of a test
+func useHashConfig(t *testing.T, algorithm string) {
+	t.Helper()
+
+	saved := []interface{}{config.PasswordHashAlgorithm, config.BcryptCost, config.Argon2Memory, config.Argon2Iterations, config.Argon2Threads}
+	t.Cleanup(func() {
+		config.PasswordHashAlgorithm = saved[0].(string)
+		config.BcryptCost = saved[1].(int)
+		config.Argon2Memory = saved[2].(int)
+		config.Argon2Iterations = saved[3].(int)
+		config.Argon2Threads = saved[4].(int)
+	})
+
+	config.PasswordHashAlgorithm = algorithm
+	config.BcryptCost = bcrypt.MinCost
+	config.Argon2Memory = 64
+	config.Argon2Iterations = 1
+	config.Argon2Threads = 1
+}
+
+func TestHashPasswordRoundTrip(t *testing.T) {
+	for _, algorithm := range []string{"bcrypt", "argon2id"} {
+		t.Run(algorithm, func(t *testing.T) {
+			useHashConfig(t, algorithm)
+
+			hash, err := HashPassword("correct horse")
+			if err != nil {
+				t.Fatalf("HashPassword: %v", err)
+			}
+
+			tests := []struct {
+				password string
+				want     bool
+			}{
+				{"correct horse", true},
+				{"correct horsE", false},
+				{"", false},
+			}
+			for _, tt := range tests {
+				if got := CheckPasswordHash(tt.password, hash); got != tt.want {
+					t.Errorf("CheckPasswordHash(%q) = %t, want %t", tt.password, got, tt.want)
+				}
+			}
+
+			if NeedsRehash(hash) {
+				t.Error("a fresh hash needs rehashing")
+			}
+		})
+	}
+}
+
+func TestCheckPasswordHashAcrossAlgorithms(t *testing.T) {
+	useHashConfig(t, "bcrypt")
+	bcryptHash, _ := HashPassword("secret")
+
+	useHashConfig(t, "argon2id")
+	argonHash, _ := HashPassword("secret")
+
+	// Stored hashes keep working after the configured algorithm changes
+	for _, hash := range []string{bcryptHash, argonHash} {
+		if !CheckPasswordHash("secret", hash) {
+			t.Errorf("CheckPasswordHash failed for %q", hash)
+		}
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	useHashConfig(t, "bcrypt")
+	bcryptHash, _ := HashPassword("secret")
+	config.BcryptCost = bcrypt.MinCost + 1
+	strongerBcryptHash, _ := HashPassword("secret")
+
+	useHashConfig(t, "argon2id")
+	argonHash, _ := HashPassword("secret")
+	config.Argon2Iterations = 2
+	slowerArgonHash, _ := HashPassword("secret")
+
+	tests := []struct {
+		name      string
+		algorithm string
+		hash      string
+		want      bool
+	}{
+		{"bcrypt at the configured cost", "bcrypt", bcryptHash, false},
+		{"bcrypt at another cost", "bcrypt", strongerBcryptHash, true},
+		{"argon2id while bcrypt is configured", "bcrypt", argonHash, true},
+		{"argon2id with the configured parameters", "argon2id", argonHash, false},
+		{"argon2id with other parameters", "argon2id", slowerArgonHash, true},
+		{"bcrypt while argon2id is configured", "argon2id", bcryptHash, true},
+		{"garbage", "bcrypt", "not a hash", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useHashConfig(t, tt.algorithm)
+			if got := NeedsRehash(tt.hash); got != tt.want {
+				t.Errorf("NeedsRehash = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeArgon2id(t *testing.T) {
+	useHashConfig(t, "argon2id")
+	hash, _ := HashPassword("secret")
+
+	memory, iterations, threads, salt, key, err := decodeArgon2id(hash)
+	if err != nil {
+		t.Fatalf("decodeArgon2id: %v", err)
+	}
+	if memory != 64 || iterations != 1 || threads != 1 || len(salt) != 16 || len(key) != argon2KeyLength {
+		t.Errorf("decoded m=%d t=%d p=%d salt=%d key=%d", memory, iterations, threads, len(salt), len(key))
+	}
+
+	parts := strings.Split(hash, "$")
+	tests := []struct {
+		name string
+		hash string
+	}{
+		{"empty", ""},
+		{"other algorithm", strings.Replace(hash, "$argon2id$", "$argon2i$", 1)},
+		{"missing part", strings.Join(parts[:5], "$")},
+		{"unsupported version", strings.Replace(hash, "v=19", "v=16", 1)},
+		{"bad parameters", strings.Replace(hash, parts[3], "m=x,t=1,p=1", 1)},
+		{"zero threads", strings.Replace(hash, parts[3], "m=64,t=1,p=0", 1)},
+		{"zero iterations", strings.Replace(hash, parts[3], "m=64,t=0,p=1", 1)},
+		{"bad salt", strings.Replace(hash, parts[4], "!!", 1)},
+		{"bad key", strings.Replace(hash, parts[5], "!!", 1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, _, _, _, err := decodeArgon2id(tt.hash); err == nil {
+				t.Errorf("decodeArgon2id(%q) succeeded", tt.hash)
+			}
+			// A hash that cannot be decoded never matches, and never panics
+			if CheckPasswordHash("secret", tt.hash) {
+				t.Errorf("CheckPasswordHash(%q) matched", tt.hash)
+			}
+		})
+	}
+}
@@ -8,44 +8,131 @@ import (
 	"time"
 )
 
-func GenerateJWT(email string) (string, error) {
-	claims := jwt.MapClaims{
-		"email": email,
-		"exp":   time.Now().Add(time.Hour * 24).Unix(),
+// Claims are the claims carried by an access token
+type Claims struct {
+	Email     string `json:"email"`
+	SessionID uint   `json:"sid,omitempty"`
+	Purpose   string `json:"purpose,omitempty"` // empty for access tokens
+	jwt.RegisteredClaims
+}
+
+// PurposeMFAChallenge marks the short-lived token issued between the password and the second factor
+const PurposeMFAChallenge = "mfa_challenge"
+
+const challengeTokenTTL = 5 * time.Minute
+
+// RevocationStore reports whether an otherwise valid token has been revoked
+type RevocationStore interface {
+	IsRevoked(claims *Claims) bool
+}
+
+var revocationStore RevocationStore
+
+// SetRevocationStore sets the store consulted by ParseJWT
+func SetRevocationStore(store RevocationStore) {
+	revocationStore = store
+}
+
+// GenerateJWT issues an access token bound to a login session
+func GenerateJWT(email string, sessionID uint) (string, error) {
+	return generateToken(email, sessionID, "", config.AccessTokenTTL)
+}
+
+// GenerateChallengeJWT issues the token that proves the password step of a two-factor login
+func GenerateChallengeJWT(email string) (string, error) {
+	return generateToken(email, 0, PurposeMFAChallenge, challengeTokenTTL)
+}
+
+func generateToken(email string, sessionID uint, purpose string, ttl time.Duration) (string, error) {
+	jti, err := GenerateToken()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims := Claims{
+		Email:     email,
+		SessionID: sessionID,
+		Purpose:   purpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+
+	key := ring.activeKey()
+	if key == nil {
+		return "", errors.New("no active signing key")
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(config.JWTSecret))
+
+	token := jwt.NewWithClaims(key.signingMethod(), claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.signKey())
 }
 
-// Parse JWT token and return email
-func ParseJWT(tokenString string) (string, error) {
-	fmt.Println("Received token:", tokenString) // Debugging output
+// ParseJWTClaims validates an access token, checks it against the revocation store and returns its claims
+func ParseJWTClaims(tokenString string) (*Claims, error) {
+	return parseToken(tokenString, "")
+}
 
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		return []byte(config.JWTSecret), nil // Ensure correct key type
+// ParseChallengeJWT validates a two-factor challenge token
+func ParseChallengeJWT(tokenString string) (*Claims, error) {
+	return parseToken(tokenString, PurposeMFAChallenge)
+}
+
+func parseToken(tokenString, purpose string) (*Claims, error) {
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key := ring.lookup(kid)
+		if key == nil {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+
+		// The algorithm is bound to the key, never to what the token header claims
+		if token.Method.Alg() != key.signingMethod().Alg() {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+		return key.verifyKey(), nil
 	})
 
 	if err != nil {
 		fmt.Println("Error parsing token:", err) // Debugging output
-		return "", errors.New("invalid token")
+		return nil, errors.New("invalid token")
 	}
 
 	if !token.Valid {
 		fmt.Println("Token is not valid") // Debugging output
-		return "", errors.New("invalid token")
+		return nil, errors.New("invalid token")
 	}
 
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok || claims["email"] == nil {
+	if claims.Email == "" || claims.ID == "" || claims.IssuedAt == nil {
 		fmt.Println("Invalid claims structure") // Debugging output
-		return "", errors.New("invalid claims")
+		return nil, errors.New("invalid claims")
+	}
+
+	// A challenge token must never work as an access token and vice versa
+	if claims.Purpose != purpose {
+		return nil, errors.New("invalid token purpose")
 	}
 
-	email, ok := claims["email"].(string)
-	if !ok {
-		fmt.Println("Invalid email claim type") // Debugging output
-		return "", errors.New("invalid email claim type")
+	if purpose == "" && claims.SessionID == 0 {
+		return nil, errors.New("invalid claims")
 	}
 
-	return email, nil
+	if revocationStore != nil && revocationStore.IsRevoked(claims) {
+		return nil, errors.New("token revoked")
+	}
+
+	return claims, nil
+}
+
+// Parse JWT token and return email
+func ParseJWT(tokenString string) (string, error) {
+	claims, err := ParseJWTClaims(tokenString)
+	if err != nil {
+		return "", err
+	}
+	return claims.Email, nil
 }
@@ -0,0 +1,322 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v4"
+	"log"
+	"material_todo_go/config"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+)
+
+// SigningKey is a JWT signing key identified by the kid header
+type SigningKey struct {
+	ID        string
+	Algorithm string
+	Secret    []byte        // HS256 only
+	Private   crypto.Signer // RS256 and EdDSA only
+	CreatedAt time.Time
+	RetiredAt *time.Time // no longer signs, still verifies until tokens signed with it expire
+}
+
+// KeyStore persists generated asymmetric keys so they survive restarts and are shared by all instances
+type KeyStore interface {
+	LoadKeys() ([]*SigningKey, error)
+	SaveKey(key *SigningKey) error
+	// RetireOlderKeys retires every unretired key except the newest one of algorithm
+	RetireOlderKeys(algorithm string, at time.Time) error
+	DeleteKey(id string) error
+}
+
+type keyring struct {
+	mu         sync.RWMutex
+	store      KeyStore
+	active     *SigningKey
+	keys       map[string]*SigningKey
+	lastReload time.Time
+}
+
+var ring = &keyring{keys: map[string]*SigningKey{}}
+
+// InitKeyring loads the signing keys for config.JWTAlgorithm and fails if none can be used.
+// Asymmetric keys are generated on first start and rotated every config.JWTKeyRotation.
+func InitKeyring(store KeyStore) error {
+	ring.store = store
+
+	switch config.JWTAlgorithm {
+	case "HS256":
+		if config.JWTSecret == "" {
+			return errors.New("JWT_SECRET is required for HS256")
+		}
+	case "RS256", "EdDSA":
+	default:
+		return fmt.Errorf("unsupported JWT_ALGORITHM %q", config.JWTAlgorithm)
+	}
+
+	if err := ring.reload(); err != nil {
+		return err
+	}
+
+	if config.JWTAlgorithm != "HS256" {
+		if err := ring.rotateIfDue(); err != nil {
+			return err
+		}
+		if config.JWTKeyRotation > 0 {
+			go ring.rotateLoop()
+		}
+	}
+
+	if ring.activeKey() == nil {
+		return errors.New("no active JWT signing key")
+	}
+
+	fmt.Printf("✅ JWT keyring loaded (%s, active kid %s)\n", config.JWTAlgorithm, ring.activeKey().ID)
+	return nil
+}
+
+// hmacKey builds a verification key from a shared secret, the kid is derived from the secret
+func hmacKey(secret string) *SigningKey {
+	sum := sha256.Sum256([]byte("kid:" + secret))
+	return &SigningKey{
+		ID:        "hs-" + hex.EncodeToString(sum[:8]),
+		Algorithm: "HS256",
+		Secret:    []byte(secret),
+	}
+}
+
+// reload rebuilds the keyring from the configured secrets and the key store
+func (r *keyring) reload() error {
+	keys := map[string]*SigningKey{}
+	var active *SigningKey
+
+	if config.JWTSecret != "" {
+		key := hmacKey(config.JWTSecret)
+		keys[key.ID] = key
+		if config.JWTAlgorithm == "HS256" {
+			active = key
+		}
+	}
+	for _, secret := range config.JWTPreviousSecrets {
+		key := hmacKey(secret)
+		keys[key.ID] = key
+	}
+
+	if config.JWTAlgorithm != "HS256" && r.store != nil {
+		stored, err := r.store.LoadKeys()
+		if err != nil {
+			return err
+		}
+
+		// Newest first, the newest unretired key of the configured algorithm signs
+		sort.Slice(stored, func(i, j int) bool { return stored[i].CreatedAt.After(stored[j].CreatedAt) })
+		for _, key := range stored {
+			if key.RetiredAt != nil && time.Since(*key.RetiredAt) > config.AccessTokenTTL {
+				// Every token signed with this key has expired
+				r.store.DeleteKey(key.ID)
+				continue
+			}
+			keys[key.ID] = key
+			if active == nil && key.RetiredAt == nil && key.Algorithm == config.JWTAlgorithm {
+				active = key
+			}
+		}
+	}
+
+	r.mu.Lock()
+	r.keys = keys
+	r.active = active
+	r.lastReload = time.Now()
+	r.mu.Unlock()
+	return nil
+}
+
+// rotateIfDue generates a new key when there is none or the active one is older than the rotation interval
+func (r *keyring) rotateIfDue() error {
+	active := r.activeKey()
+	if active != nil && (config.JWTKeyRotation <= 0 || time.Since(active.CreatedAt) < config.JWTKeyRotation) {
+		return nil
+	}
+
+	key, err := generateSigningKey(config.JWTAlgorithm)
+	if err != nil {
+		return err
+	}
+	if err := r.store.SaveKey(key); err != nil {
+		return err
+	}
+	// Instances rotating at the same time all keep the newest key, so no extra key stays active
+	if err := r.store.RetireOlderKeys(config.JWTAlgorithm, time.Now()); err != nil {
+		return err
+	}
+
+	log.Printf("Rotated JWT signing key, new kid %s", key.ID)
+	return r.reload()
+}
+
+// rotateLoop periodically picks up keys rotated by other instances and rotates when due
+func (r *keyring) rotateLoop() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := r.reload(); err != nil {
+			log.Printf("Failed to reload JWT keys: %v", err)
+			continue
+		}
+		if err := r.rotateIfDue(); err != nil {
+			log.Printf("Failed to rotate JWT signing key: %v", err)
+		}
+	}
+}
+
+func (r *keyring) activeKey() *SigningKey {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.active
+}
+
+// lookup finds a key by kid, reloading once in a while in case another instance rotated
+func (r *keyring) lookup(kid string) *SigningKey {
+	r.mu.RLock()
+	key := r.keys[kid]
+	stale := time.Since(r.lastReload) > 10*time.Second
+	r.mu.RUnlock()
+
+	if key == nil && stale && r.store != nil && config.JWTAlgorithm != "HS256" {
+		if err := r.reload(); err == nil {
+			r.mu.RLock()
+			key = r.keys[kid]
+			r.mu.RUnlock()
+		}
+	}
+	return key
+}
+
+func generateSigningKey(algorithm string) (*SigningKey, error) {
+	var private crypto.Signer
+	var err error
+
+	switch algorithm {
+	case "RS256":
+		private, err = rsa.GenerateKey(rand.Reader, 2048)
+	case "EdDSA":
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		err = fmt.Errorf("cannot generate keys for %q", algorithm)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	return &SigningKey{
+		ID:        hex.EncodeToString(id),
+		Algorithm: algorithm,
+		Private:   private,
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+// signingMethod returns the jwt signing method for the key's algorithm
+func (k *SigningKey) signingMethod() jwt.SigningMethod {
+	switch k.Algorithm {
+	case "RS256":
+		return jwt.SigningMethodRS256
+	case "EdDSA":
+		return jwt.SigningMethodEdDSA
+	default:
+		return jwt.SigningMethodHS256
+	}
+}
+
+func (k *SigningKey) signKey() interface{} {
+	if k.Algorithm == "HS256" {
+		return k.Secret
+	}
+	return k.Private
+}
+
+func (k *SigningKey) verifyKey() interface{} {
+	if k.Algorithm == "HS256" {
+		return k.Secret
+	}
+	return k.Private.Public()
+}
+
+// EncodePrivateKey returns the PKCS#8 PEM encoding of an asymmetric key
+func (k *SigningKey) EncodePrivateKey() (string, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(k.Private)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), nil
+}
+
+// DecodePrivateKey parses a PKCS#8 PEM key produced by EncodePrivateKey
+func DecodePrivateKey(encoded string) (crypto.Signer, error) {
+	block, _ := pem.Decode([]byte(encoded))
+	if block == nil {
+		return nil, errors.New("invalid PEM key")
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("unsupported private key type")
+	}
+	return signer, nil
+}
+
+// JWKS returns the public keys that verify tokens issued by this backend, in JWK Set format.
+// Shared HS256 secrets are never published.
+func JWKS() map[string]interface{} {
+	ring.mu.RLock()
+	defer ring.mu.RUnlock()
+
+	keys := []map[string]interface{}{}
+	for _, key := range ring.keys {
+		switch public := key.verifyKey().(type) {
+		case *rsa.PublicKey:
+			keys = append(keys, map[string]interface{}{
+				"kty": "RSA",
+				"use": "sig",
+				"alg": key.Algorithm,
+				"kid": key.ID,
+				"n":   base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			keys = append(keys, map[string]interface{}{
+				"kty": "OKP",
+				"crv": "Ed25519",
+				"use": "sig",
+				"alg": key.Algorithm,
+				"kid": key.ID,
+				"x":   base64.RawURLEncoding.EncodeToString(public),
+			})
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i]["kid"].(string) < keys[j]["kid"].(string) })
+	return map[string]interface{}{"keys": keys}
+}
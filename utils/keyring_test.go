@@ -0,0 +1,262 @@
+package utils
+
+import (
+	"material_todo_go/config"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// memoryKeyStore is a KeyStore kept in memory
+type memoryKeyStore struct {
+	mu   sync.Mutex
+	keys map[string]*SigningKey
+}
+
+func newMemoryKeyStore() *memoryKeyStore {
+	return &memoryKeyStore{keys: map[string]*SigningKey{}}
+}
+
+func (s *memoryKeyStore) LoadKeys() ([]*SigningKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := []*SigningKey{}
+	for _, key := range s.keys {
+		copied := *key
+		keys = append(keys, &copied)
+	}
+	return keys, nil
+}
+
+func (s *memoryKeyStore) SaveKey(key *SigningKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	copied := *key
+	s.keys[key.ID] = &copied
+	return nil
+}
+
+func (s *memoryKeyStore) RetireOlderKeys(algorithm string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var newest *SigningKey
+	for _, key := range s.keys {
+		if key.Algorithm == algorithm && key.RetiredAt == nil && (newest == nil || key.CreatedAt.After(newest.CreatedAt)) {
+			newest = key
+		}
+	}
+	for _, key := range s.keys {
+		if key != newest && key.RetiredAt == nil {
+			retiredAt := at
+			key.RetiredAt = &retiredAt
+		}
+	}
+	return nil
+}
+
+func (s *memoryKeyStore) DeleteKey(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.keys, id)
+	return nil
+}
+
+func (s *memoryKeyStore) unretired() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := []string{}
+	for id, key := range s.keys {
+		if key.RetiredAt == nil {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// useKeyring resets the keyring and the JWT settings for the duration of a test
+func useKeyring(t *testing.T, algorithm string, store KeyStore) {
+	t.Helper()
+
+	savedAlgorithm, savedSecret, savedPrevious := config.JWTAlgorithm, config.JWTSecret, config.JWTPreviousSecrets
+	savedRotation, savedTTL, savedRing, savedRevocations := config.JWTKeyRotation, config.AccessTokenTTL, ring, revocationStore
+	t.Cleanup(func() {
+		config.JWTAlgorithm, config.JWTSecret, config.JWTPreviousSecrets = savedAlgorithm, savedSecret, savedPrevious
+		config.JWTKeyRotation, config.AccessTokenTTL, ring, revocationStore = savedRotation, savedTTL, savedRing, savedRevocations
+	})
+
+	config.JWTAlgorithm = algorithm
+	config.JWTSecret = "current-secret"
+	config.JWTPreviousSecrets = []string{"previous-secret"}
+	config.JWTKeyRotation = 0 // no background rotation loop
+	config.AccessTokenTTL = 15 * time.Minute
+	ring = &keyring{keys: map[string]*SigningKey{}}
+	revocationStore = nil
+
+	if err := InitKeyring(store); err != nil {
+		t.Fatalf("InitKeyring: %v", err)
+	}
+}
+
+// signWith signs access token claims with a key, under the given kid
+func signWith(t *testing.T, key *SigningKey, kid string) string {
+	t.Helper()
+
+	now := time.Now()
+	token := jwt.NewWithClaims(key.signingMethod(), Claims{
+		Email:     "user@example.com",
+		SessionID: 1,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        "jti",
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+		},
+	})
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key.signKey())
+	if err != nil {
+		t.Fatalf("SignedString: %v", err)
+	}
+	return signed
+}
+
+func TestKeyringSignAndVerify(t *testing.T) {
+	for _, algorithm := range []string{"HS256", "RS256", "EdDSA"} {
+		t.Run(algorithm, func(t *testing.T) {
+			useKeyring(t, algorithm, newMemoryKeyStore())
+
+			token, err := GenerateJWT("user@example.com", 7)
+			if err != nil {
+				t.Fatalf("GenerateJWT: %v", err)
+			}
+			claims, err := ParseJWTClaims(token)
+			if err != nil {
+				t.Fatalf("ParseJWTClaims: %v", err)
+			}
+			if claims.Email != "user@example.com" || claims.SessionID != 7 {
+				t.Errorf("claims = %+v", claims)
+			}
+
+			// Access and challenge tokens are not interchangeable
+			if _, err := ParseChallengeJWT(token); err == nil {
+				t.Error("an access token was accepted as a challenge")
+			}
+			challenge, _ := GenerateChallengeJWT("user@example.com")
+			if _, err := ParseJWTClaims(challenge); err == nil {
+				t.Error("a challenge was accepted as an access token")
+			}
+			if _, err := ParseChallengeJWT(challenge); err != nil {
+				t.Errorf("ParseChallengeJWT: %v", err)
+			}
+		})
+	}
+}
+
+func TestKeyringRejectsForeignTokens(t *testing.T) {
+	useKeyring(t, "HS256", nil)
+	active := ring.activeKey()
+	previous := hmacKey("previous-secret")
+	unknown := hmacKey("unknown-secret")
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{"active key", signWith(t, active, active.ID), false},
+		{"previous secret still verifies", signWith(t, previous, previous.ID), false},
+		{"unknown kid", signWith(t, active, "unknown-kid"), true},
+		{"missing kid", signWith(t, active, ""), true},
+		{"known kid, wrong secret", signWith(t, unknown, active.ID), true},
+		{"not a token", "not-a-token", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseJWTClaims(tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseJWTClaims error = %v, want error %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestKeyringRejectsAlgorithmSwitch(t *testing.T) {
+	useKeyring(t, "RS256", newMemoryKeyStore())
+	active := ring.activeKey()
+
+	// An HMAC token under the RSA kid must not verify with the public key as secret
+	forged := &SigningKey{Algorithm: "HS256", Secret: []byte("public key bytes")}
+	if _, err := ParseJWTClaims(signWith(t, forged, active.ID)); err == nil {
+		t.Error("HS256 token accepted for an RS256 key")
+	}
+}
+
+func TestKeyringRotation(t *testing.T) {
+	store := newMemoryKeyStore()
+	useKeyring(t, "EdDSA", store)
+
+	old := ring.activeKey()
+	oldToken, _ := GenerateJWT("user@example.com", 1)
+
+	// Age the active key past the rotation interval
+	config.JWTKeyRotation = time.Hour
+	store.keys[old.ID].CreatedAt = time.Now().Add(-2 * time.Hour)
+	if err := ring.reload(); err != nil {
+		t.Fatal(err)
+	}
+	if err := ring.rotateIfDue(); err != nil {
+		t.Fatalf("rotateIfDue: %v", err)
+	}
+
+	current := ring.activeKey()
+	if current.ID == old.ID {
+		t.Fatal("the key was not rotated")
+	}
+	if got := store.unretired(); len(got) != 1 || got[0] != current.ID {
+		t.Errorf("unretired keys = %v, want only %s", got, current.ID)
+	}
+
+	// The retired key keeps verifying tokens it signed until they expire
+	if _, err := ParseJWTClaims(oldToken); err != nil {
+		t.Errorf("token of the retired key rejected: %v", err)
+	}
+
+	// Once every token it signed has expired, the retired key is dropped
+	retiredAt := time.Now().Add(-config.AccessTokenTTL - time.Minute)
+	store.keys[old.ID].RetiredAt = &retiredAt
+	if err := ring.reload(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseJWTClaims(oldToken); err == nil {
+		t.Error("token of an expired retired key accepted")
+	}
+	if _, ok := store.keys[old.ID]; ok {
+		t.Error("expired retired key was not deleted")
+	}
+}
+
+func TestConcurrentRotationsKeepOneActiveKey(t *testing.T) {
+	store := newMemoryKeyStore()
+	useKeyring(t, "EdDSA", store)
+
+	// Two instances that both found the key due save a new key each before retiring
+	first, _ := generateSigningKey("EdDSA")
+	second, _ := generateSigningKey("EdDSA")
+	second.CreatedAt = first.CreatedAt.Add(time.Millisecond)
+	store.SaveKey(first)
+	store.SaveKey(second)
+	store.RetireOlderKeys("EdDSA", time.Now())
+	store.RetireOlderKeys("EdDSA", time.Now())
+
+	if got := store.unretired(); len(got) != 1 || got[0] != second.ID {
+		t.Errorf("unretired keys = %v, want only %s", got, second.ID)
+	}
+}
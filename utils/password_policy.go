@@ -0,0 +1,106 @@
+package utils
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"golang.org/x/crypto/bcrypt"
+	"material_todo_go/config"
+	"math"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// bcryptMaxBytes is the longest input bcrypt accepts
+const bcryptMaxBytes = 72
+
+// breachedPasswords holds upper-case SHA-1 hex digests of known breached passwords
+var breachedPasswords = map[string]struct{}{}
+
+// InitPasswordPolicy checks the hashing configuration and loads config.PasswordBreachedList.
+// Each line of the list is either a plain password or a SHA-1 hex digest, optionally followed
+// by ":count" as in the Have I Been Pwned downloads.
+func InitPasswordPolicy() error {
+	if config.PasswordHashAlgorithm != "bcrypt" && config.PasswordHashAlgorithm != "argon2id" {
+		return fmt.Errorf("unsupported PASSWORD_HASH_ALGORITHM %q", config.PasswordHashAlgorithm)
+	}
+
+	if config.PasswordHashAlgorithm == "bcrypt" {
+		if config.BcryptCost < bcrypt.MinCost || config.BcryptCost > bcrypt.MaxCost {
+			return fmt.Errorf("BCRYPT_COST must be between %d and %d, got %d", bcrypt.MinCost, bcrypt.MaxCost, config.BcryptCost)
+		}
+		// bcrypt refuses longer input, so a larger limit would only turn into failed hashes
+		if config.PasswordMaxLength > bcryptMaxBytes {
+			config.PasswordMaxLength = bcryptMaxBytes
+		}
+	}
+
+	// argon2.IDKey panics on parameters it cannot use, so they are checked before the first hash
+	if config.PasswordHashAlgorithm == "argon2id" {
+		if config.Argon2Threads < 1 || config.Argon2Threads > 255 {
+			return fmt.Errorf("ARGON2_THREADS must be between 1 and 255, got %d", config.Argon2Threads)
+		}
+		if config.Argon2Iterations < 1 || int64(config.Argon2Iterations) > math.MaxUint32 {
+			return fmt.Errorf("ARGON2_ITERATIONS must be at least 1, got %d", config.Argon2Iterations)
+		}
+		if config.Argon2Memory < 8*config.Argon2Threads || int64(config.Argon2Memory) > math.MaxUint32 {
+			return fmt.Errorf("ARGON2_MEMORY must be at least %d KiB for %d threads, got %d", 8*config.Argon2Threads, config.Argon2Threads, config.Argon2Memory)
+		}
+	}
+
+	if config.PasswordBreachedList == "" {
+		return nil
+	}
+
+	file, err := os.Open(config.PasswordBreachedList)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		digest := strings.ToUpper(strings.SplitN(line, ":", 2)[0])
+		if _, err := hex.DecodeString(digest); err != nil || len(digest) != sha1.Size*2 {
+			digest = sha1Hex(line)
+		}
+		breachedPasswords[digest] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ Loaded %d breached passwords\n", len(breachedPasswords))
+	return nil
+}
+
+func sha1Hex(value string) string {
+	sum := sha1.Sum([]byte(value))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// ValidatePassword checks a new password against the configured policy
+func ValidatePassword(password string) error {
+	length := utf8.RuneCountInString(password)
+	if length < config.PasswordMinLength {
+		return fmt.Errorf("Password must be at least %d characters long", config.PasswordMinLength)
+	}
+	if length > config.PasswordMaxLength {
+		return fmt.Errorf("Password must be at most %d characters long", config.PasswordMaxLength)
+	}
+	if config.PasswordHashAlgorithm == "bcrypt" && len(password) > bcryptMaxBytes {
+		return fmt.Errorf("Password must be at most %d bytes long", bcryptMaxBytes)
+	}
+
+	if _, found := breachedPasswords[sha1Hex(password)]; found {
+		return fmt.Errorf("This password has appeared in a data breach, please choose another one")
+	}
+	return nil
+}
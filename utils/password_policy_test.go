@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"material_todo_go/config"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// usePolicyConfig sets the policy limits for the duration of a test and forgets loaded breached passwords
+func usePolicyConfig(t *testing.T, algorithm string, minLength, maxLength int) {
+	t.Helper()
+	useHashConfig(t, algorithm)
+
+	savedMin, savedMax, savedList, savedBreached := config.PasswordMinLength, config.PasswordMaxLength, config.PasswordBreachedList, breachedPasswords
+	t.Cleanup(func() {
+		config.PasswordMinLength, config.PasswordMaxLength, config.PasswordBreachedList, breachedPasswords = savedMin, savedMax, savedList, savedBreached
+	})
+
+	config.PasswordMinLength = minLength
+	config.PasswordMaxLength = maxLength
+	config.PasswordBreachedList = ""
+	breachedPasswords = map[string]struct{}{}
+}
+
+func TestValidatePassword(t *testing.T) {
+	tests := []struct {
+		name      string
+		algorithm string
+		password  string
+		wantErr   string
+	}{
+		{"long enough", "bcrypt", "abcdefgh", ""},
+		{"too short", "bcrypt", "abcdefg", "at least 8 characters"},
+		{"length counts characters, not bytes", "argon2id", "ééééééé", "at least 8 characters"},
+		{"too long", "argon2id", strings.Repeat("a", 101), "at most 100 characters"},
+		{"bcrypt byte limit", "bcrypt", strings.Repeat("a", 72), ""},
+		{"over the bcrypt byte limit", "bcrypt", strings.Repeat("a", 73), "at most 72 bytes"},
+		{"multibyte over the bcrypt byte limit", "bcrypt", strings.Repeat("é", 40), "at most 72 bytes"},
+		{"multibyte fine with argon2id", "argon2id", strings.Repeat("é", 40), ""},
+		{"breached", "bcrypt", "password123", "data breach"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usePolicyConfig(t, tt.algorithm, 8, 100)
+			breachedPasswords[sha1Hex("password123")] = struct{}{}
+
+			err := ValidatePassword(tt.password)
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("ValidatePassword = %v, want nil", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("ValidatePassword = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestInitPasswordPolicyLoadsBreachedList(t *testing.T) {
+	usePolicyConfig(t, "bcrypt", 8, 72)
+
+	list := filepath.Join(t.TempDir(), "breached.txt")
+	content := "plaintext-one\n\n" + sha1Hex("hashed-two") + ":42\n" + strings.ToLower(sha1Hex("hashed-three")) + "\n"
+	if err := os.WriteFile(list, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	config.PasswordBreachedList = list
+
+	if err := InitPasswordPolicy(); err != nil {
+		t.Fatalf("InitPasswordPolicy: %v", err)
+	}
+	for _, password := range []string{"plaintext-one", "hashed-two", "hashed-three"} {
+		if err := ValidatePassword(password); err == nil {
+			t.Errorf("%q is not reported as breached", password)
+		}
+	}
+	if err := ValidatePassword("not-in-the-list"); err != nil {
+		t.Errorf("ValidatePassword = %v, want nil", err)
+	}
+}
+
+func TestInitPasswordPolicyChecksHashing(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func()
+		wantErr bool
+		wantMax int
+	}{
+		{"bcrypt clamps the maximum length", func() {}, false, 72},
+		{"unknown algorithm", func() { config.PasswordHashAlgorithm = "md5" }, true, 128},
+		{"bcrypt cost too low", func() { config.BcryptCost = 3 }, true, 128},
+		{"bcrypt cost too high", func() { config.BcryptCost = 32 }, true, 128},
+		{"argon2id keeps the maximum length", func() { config.PasswordHashAlgorithm = "argon2id" }, false, 128},
+		{"argon2id without threads", func() { config.PasswordHashAlgorithm = "argon2id"; config.Argon2Threads = 0 }, true, 128},
+		{"argon2id with too many threads", func() { config.PasswordHashAlgorithm = "argon2id"; config.Argon2Threads = 256 }, true, 128},
+		{"argon2id without iterations", func() { config.PasswordHashAlgorithm = "argon2id"; config.Argon2Iterations = 0 }, true, 128},
+		{"argon2id with too little memory", func() {
+			config.PasswordHashAlgorithm = "argon2id"
+			config.Argon2Threads = 4
+			config.Argon2Memory = 31
+		}, true, 128},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usePolicyConfig(t, "bcrypt", 8, 128)
+			tt.setup()
+
+			err := InitPasswordPolicy()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("InitPasswordPolicy = %v, want error %t", err, tt.wantErr)
+			}
+			if !tt.wantErr && config.PasswordMaxLength != tt.wantMax {
+				t.Errorf("PasswordMaxLength = %d, want %d", config.PasswordMaxLength, tt.wantMax)
+			}
+		})
+	}
+}